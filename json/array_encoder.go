@@ -0,0 +1,65 @@
+package json
+
+import "fmt"
+
+// ArrayEncoder writes a JSON array one element at a time, so arrays too large to hold in memory can be streamed.
+// Call Open, then Write for each element, then Close.
+type ArrayEncoder struct {
+	encoder *Encoder
+	opened  bool
+	closed  bool
+	count   int
+}
+
+// NewArrayEncoder returns an ArrayEncoder writing through e, using its options and indentation.
+func NewArrayEncoder(e *Encoder) *ArrayEncoder {
+	return &ArrayEncoder{encoder: e}
+}
+
+// Open writes the opening [ of the array.
+func (a *ArrayEncoder) Open() error {
+	if a.opened {
+		return fmt.Errorf("cannot Open array, already opened")
+	}
+	a.opened = true
+	return a.encoder.openCollection('[')
+}
+
+// Write writes value as the next element of the array.
+func (a *ArrayEncoder) Write(value interface{}) error {
+	if err := a.checkOpen("Write array value"); err != nil {
+		return err
+	}
+	if err := a.encoder.nextElement(a.count == 0); err != nil {
+		return err
+	}
+	if err := a.encoder.marshalValue(value); err != nil {
+		return withPathElement(a.count, err)
+	}
+	a.count += 1
+	return nil
+}
+
+// Close writes the closing ] of the array and flushes it to the output.
+func (a *ArrayEncoder) Close() error {
+	if err := a.checkOpen("Close array"); err != nil {
+		return err
+	}
+	a.closed = true
+	if err := a.encoder.closeCollection(']', a.count == 0); err != nil {
+		return err
+	}
+	if err := a.encoder.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
+
+func (a *ArrayEncoder) checkOpen(action string) error {
+	if !a.opened {
+		return fmt.Errorf("cannot %s, array was never opened", action)
+	} else if a.closed {
+		return fmt.Errorf("cannot %s, array is already closed", action)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// MarshalCanonical writes value in a canonical form modeled on RFC 8785, so equal values always produce identical
+// bytes for hashing and signing:
+//   - no whitespace between tokens
+//   - object members, including those of an OrderedObject, sorted by the UTF-16 code units of their keys
+//   - strings escaping only ", \ and control characters, using \b \f \n \r \t where possible and lowercase \u00xx
+//     otherwise
+//   - integers in plain decimal
+//   - floats, and Numbers that are not int64, in the shortest form that round-trips as a float64, switching to
+//     exponent form such as 1e+21 or 1e-7 outside the range 1e-6 to 1e21, and with -0 written as 0
+//
+// Output from a Marshaler is parsed and written again in canonical form. The encoder options are ignored.
+func MarshalCanonical(value interface{}, writer *bufio.Writer) error {
+	e := newEncoder(writer)
+	e.canonical = true
+	e.escapeHTML = false
+	return e.marshalValue(value)
+}
+
+func (e *Encoder) marshalCanonicalMarshaler(marshaler Marshaler) error {
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	if err := marshaler.MarshalJSONValue(writer); err != nil {
+		return fmt.Errorf("failed to marshal %T with MarshalJSONValue: %w", marshaler, err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	d := newDecoder(bufio.NewReader(&buf))
+	d.UseNumber = true
+	value, err := d.unmarshalComplete()
+	if err != nil {
+		return fmt.Errorf("invalid JSON from %T MarshalJSONValue: %w", marshaler, d.wrapError(err))
+	}
+	return e.marshalValue(value)
+}
+
+func (e *Encoder) marshalCanonicalNumber(number Number) error {
+	var valueString string
+	if valueInt64, err := number.Int64(); err == nil {
+		valueString = strconv.FormatInt(valueInt64, 10)
+	} else {
+		valueFloat64, err := number.Float64()
+		if err != nil {
+			return fmt.Errorf("cannot represent number %s as a float64: %w", number, err)
+		}
+		valueString = formatCanonicalFloat(valueFloat64)
+	}
+	if _, err := e.writer.WriteString(valueString); err != nil {
+		return fmt.Errorf("failed to write value %s: %w", valueString, err)
+	}
+	return nil
+}
+
+// formatCanonicalFloat formats a finite float the way ECMAScript's Number.prototype.toString does.
+func formatCanonicalFloat(value float64) string {
+	if value == 0 {
+		// Includes -0
+		return "0"
+	}
+	return formatFloat(value, -1, 64)
+}
+
+func sortCanonical(members []objectMember) {
+	sort.Slice(members, func(i, j int) bool {
+		return utf16Less(members[i].key, members[j].key)
+	})
+}
+
+// utf16Less reports whether a sorts before b when both are compared as sequences of UTF-16 code units.
+func utf16Less(a, b string) bool {
+	for a != "" && b != "" {
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			// Runes outside the Basic Multilingual Plane start with a surrogate, which sorts below U+E000 to U+FFFF.
+			// Two such runes sharing a high surrogate compare the same way as their low surrogates.
+			unitA, unitB := firstUTF16Unit(ra), firstUTF16Unit(rb)
+			if unitA != unitB {
+				return unitA < unitB
+			}
+			return ra < rb
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return a == "" && b != ""
+}
+
+func firstUTF16Unit(r rune) rune {
+	if r > 0xFFFF {
+		high, _ := utf16.EncodeRune(r)
+		return high
+	}
+	return r
+}
@@ -0,0 +1,242 @@
+package json
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+const DEFAULT_MAX_DEPTH = 10000
+
+// Decoder reads JSON values from an input stream.
+type Decoder struct {
+	reader *positionReader
+	// UseOrderedObjects makes objects decode as *OrderedObject instead of map[string]interface{}, preserving key order.
+	UseOrderedObjects bool
+	// DisallowDuplicateKeys makes a key appearing twice in the same object an error instead of overwriting the first value.
+	DisallowDuplicateKeys bool
+	// DuplicateKeyPolicy chooses what happens when a key appears twice in the same object. DisallowDuplicateKeys
+	// takes precedence over it.
+	DuplicateKeyPolicy DuplicateKeyPolicy
+	// UseNumber makes numbers decode as Number instead of int64 or float64, preserving their exact text.
+	UseNumber bool
+	// NumberMode chooses the Go type of numbers decoded into interface{} values. UseNumber takes precedence over it.
+	NumberMode NumberMode
+	// AllowControlCharacters accepts unescaped control characters (U+0000 through U+001F) inside strings.
+	AllowControlCharacters bool
+	// StrictUTF8 rejects strings holding bytes that are not valid UTF-8. By default each invalid byte is read as
+	// U+FFFD, the Unicode replacement character.
+	StrictUTF8 bool
+	// LenientEscapes accepts a backslash before any character in a string, taking an unknown escape such as \x or \'
+	// as the character after the backslash. The \u escape is always checked.
+	LenientEscapes bool
+	// AllowComments treats // line comments and /* block comments */ as whitespace.
+	AllowComments bool
+	// AllowTrailingComma accepts a single comma after the last element of an array or member of an object.
+	AllowTrailingComma bool
+	// DisallowUnknownFields makes DecodeInto fail when an object key matches no field of the target struct.
+	DisallowUnknownFields bool
+	// LenientBools makes DecodeInto accept the numbers 0 and 1 and the strings "false" and "true" for bool targets.
+	LenientBools bool
+	// TruncateArrays makes DecodeInto discard elements that do not fit in a Go array instead of failing.
+	TruncateArrays bool
+	// DisallowBOM rejects a UTF-8 byte order mark at the start of the input. By default a single one is skipped there,
+	// as files written on Windows often begin with one. A byte order mark anywhere else is always an error.
+	DisallowBOM bool
+	// MaxBytes is the most input a single call to Decode or DecodeInto may consume, counting the whitespace around the
+	// value. Decoding fails once it is exceeded, without reading the rest of the value. Zero or less means no limit.
+	MaxBytes int64
+	// MaxStringLen is the longest string, in bytes after unescaping, allowed as a value or object key. Decoding fails
+	// as soon as a string exceeds it, without reading the rest. Zero or less means no limit.
+	MaxStringLen int
+	// MaxDepth is the deepest nesting of objects and arrays allowed before decoding fails. Zero or less means no limit.
+	MaxDepth int
+	depth    int
+	// State of the Token stream and the states of the enclosing arrays and objects
+	tokenState int
+	tokenStack []int
+	// Object keys and array indices leading to the value being parsed, and a copy of them where parsing failed
+	path      []interface{}
+	errorPath []interface{}
+	// Set during DecodeContext
+	ctx context.Context
+	// Interned object keys
+	keys map[string]string
+	// Set by RegisterTypeDecoder
+	typeDecoders map[reflect.Type]func([]byte, reflect.Value) error
+}
+
+// DuplicateKeyPolicy chooses which value is kept when a key appears more than once in an object.
+type DuplicateKeyPolicy int
+
+const (
+	// DUPLICATE_KEY_LAST_WINS keeps the last value, overwriting earlier ones.
+	DUPLICATE_KEY_LAST_WINS DuplicateKeyPolicy = iota
+	// DUPLICATE_KEY_FIRST_WINS keeps the first value. Later values are parsed and discarded.
+	DUPLICATE_KEY_FIRST_WINS
+	// DUPLICATE_KEY_ERROR fails on the second occurrence of a key. It is the same as DisallowDuplicateKeys.
+	DUPLICATE_KEY_ERROR
+)
+
+// duplicateKeyPolicy returns the DuplicateKeyPolicy in effect, which DisallowDuplicateKeys overrides.
+func (d *Decoder) duplicateKeyPolicy() DuplicateKeyPolicy {
+	if d.DisallowDuplicateKeys {
+		return DUPLICATE_KEY_ERROR
+	}
+	return d.DuplicateKeyPolicy
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return newDecoder(bufio.NewReader(r))
+}
+
+// newDecoder returns a Decoder reading from reader directly, so no bytes are buffered beyond what reader holds.
+func newDecoder(reader *bufio.Reader) *Decoder {
+	return &Decoder{reader: newPositionReader(reader), MaxDepth: DEFAULT_MAX_DEPTH}
+}
+
+// newBytesDecoder returns a Decoder that parses data in place, without copying it into a buffer.
+func newBytesDecoder(data []byte) *Decoder {
+	return &Decoder{reader: newBytesPositionReader(data), MaxDepth: DEFAULT_MAX_DEPTH}
+}
+
+// Decode reads the next JSON value from the input. It may be called repeatedly to read a stream of values
+// separated by whitespace, and returns io.EOF itself, unwrapped, once only whitespace remains, so callers can compare
+// err == io.EOF. A value cut off by the end of the input is an error wrapping io.ErrUnexpectedEOF instead.
+func (d *Decoder) Decode() (interface{}, error) {
+	value, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// DecodePartial is like Decode, but when decoding fails it also returns what was parsed before the error, to help
+// debug malformed input. Arrays and objects hold the elements and members completed so far, along with any array or
+// object that was cut off partway, so the value is nil only if the error came before any array or object was started.
+func (d *Decoder) DecodePartial() (interface{}, error) {
+	return d.decode()
+}
+
+func (d *Decoder) decode() (interface{}, error) {
+	d.reader.setLimit(d.MaxBytes)
+	if err := d.prepareTokenForDecode(); err != nil {
+		return nil, d.wrapError(err)
+	}
+	eof, err := d.atEOF()
+	if err != nil {
+		return nil, d.wrapError(err)
+	}
+	if eof && len(d.tokenStack) == 0 {
+		return nil, io.EOF
+	}
+	value, err := d.unmarshalValue()
+	if err != nil {
+		return value, d.wrapError(err)
+	}
+	d.tokenValueEnd()
+	return value, nil
+}
+
+// DecodeContext is like Decode but gives up with an error wrapping ctx.Err() once ctx is done. The context is checked
+// before each value, including every array element and object member, so a read blocked on the underlying reader is
+// not interrupted.
+func (d *Decoder) DecodeContext(ctx context.Context) (interface{}, error) {
+	d.ctx = ctx
+	defer func() {
+		d.ctx = nil
+	}()
+	return d.Decode()
+}
+
+// checkContext fails once the context passed to DecodeContext is done.
+func (d *Decoder) checkContext() error {
+	if d.ctx == nil {
+		return nil
+	}
+	select {
+	case <-d.ctx.Done():
+		return fmt.Errorf("stopped decoding: %w", d.ctx.Err())
+	default:
+		return nil
+	}
+}
+
+func (d *Decoder) enterNesting() error {
+	d.depth += 1
+	if d.MaxDepth > 0 && d.depth > d.MaxDepth {
+		d.depth -= 1
+		return fmt.Errorf("exceeded maximum nesting depth of %d", d.MaxDepth)
+	}
+	return nil
+}
+
+func (d *Decoder) exitNesting() {
+	d.depth -= 1
+}
+
+// atEOF consumes whitespace and reports whether the input has ended.
+func (d *Decoder) atEOF() (bool, error) {
+	if err := d.unmarshalWhitespace(); err != nil {
+		return false, err
+	}
+	_, _, err := d.reader.ReadRune()
+	if err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to read rune: %w", err)
+	}
+	if err := d.reader.UnreadRune(); err != nil {
+		return false, fmt.Errorf("failed to unread rune: %w", err)
+	}
+	return false, nil
+}
+
+// expectEOF fails if anything but whitespace remains in the input.
+func (d *Decoder) expectEOF() error {
+	eof, err := d.atEOF()
+	if err != nil {
+		return err
+	}
+	if !eof {
+		r, err := d.peekRune()
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("unexpected trailing data after top-level value, found: %c", r)
+	}
+	return nil
+}
+
+// recordErrorPath saves the current path as the location of an error on its way out. The innermost container records
+// it first, so the outer ones leave it alone. It reports whether this container was the innermost one, as only that one
+// adds to the error message: wrapping it again at every level of deeply nested input would take quadratic time.
+func (d *Decoder) recordErrorPath() bool {
+	innermost := d.errorPath == nil
+	if innermost {
+		d.errorPath = append([]interface{}{}, d.path...)
+	}
+	d.path = d.path[:len(d.path)-1]
+	return innermost
+}
+
+// nestedErrorRecorded reports whether an error on its way out came from inside a nested array or object, which has
+// described it and recorded its path already. Enclosing values pass such an error on without wrapping it again.
+func (d *Decoder) nestedErrorRecorded() bool {
+	return d.errorPath != nil
+}
+
+// wrapError returns a SyntaxError wrapping err at the current position, or nil if err is nil. If err happened inside
+// an array or object, it is first wrapped in a PathError.
+func (d *Decoder) wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if d.errorPath != nil {
+		err = &PathError{Elements: d.errorPath, Err: err}
+		d.errorPath = nil
+	}
+	return d.reader.wrapError(err)
+}
@@ -0,0 +1,162 @@
+package json
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodeStream(t *testing.T) {
+	d := NewDecoder(strings.NewReader("{\"id\":1}\n{\"id\":2} \r\n\t{\"id\":3}\n"))
+	var values []interface{}
+	for {
+		value, err := d.Decode()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode failed after %d values: %v", len(values), err)
+		}
+		values = append(values, value)
+	}
+	want := []interface{}{
+		map[string]interface{}{"id": int64(1)},
+		map[string]interface{}{"id": int64(2)},
+		map[string]interface{}{"id": int64(3)},
+	}
+	if !Equal(values, want) {
+		t.Errorf("Decode returned %#v; want %#v", values, want)
+	}
+}
+
+func TestDecodeComments(t *testing.T) {
+	tests := []struct {
+		input string
+		want  interface{}
+	}{
+		{"// top\n[1, /* two */ 2 // end\n]", []interface{}{int64(1), int64(2)}},
+		{`{/*a*/"a" /*b*/ : /*c*/ 1 /*d*/, "b": 2}`, map[string]interface{}{"a": int64(1), "b": int64(2)}},
+		{"/* leading */ true // trailing", true},
+		{`"a // b /* c */"`, "a // b /* c */"},
+	}
+	for _, test := range tests {
+		d := NewDecoder(strings.NewReader(test.input))
+		d.AllowComments = true
+		value, err := d.Decode()
+		if err != nil {
+			t.Errorf("Decode(%q) with AllowComments failed: %v", test.input, err)
+		} else if !Equal(value, test.want) {
+			t.Errorf("Decode(%q) with AllowComments = %#v; want %#v", test.input, value, test.want)
+		}
+		if test.input[0] != '"' {
+			if _, err := NewDecoder(strings.NewReader(test.input)).Decode(); err == nil {
+				t.Errorf("Decode(%q) accepted comments without AllowComments", test.input)
+			}
+		}
+	}
+	d := NewDecoder(strings.NewReader(`[1 /* unterminated`))
+	d.AllowComments = true
+	if _, err := d.Decode(); err == nil || !strings.Contains(err.Error(), "unterminated block comment") {
+		t.Errorf("Decode of an unterminated block comment = %v; want an unterminated block comment error", err)
+	}
+}
+
+func TestDecodeIntoRawMessageExcludesComments(t *testing.T) {
+	var target struct {
+		A RawMessage
+		B int
+	}
+	d := NewDecoder(strings.NewReader(`{"A": [1, 2] /* c */ ,"B": 2}`))
+	d.AllowComments = true
+	if err := d.DecodeInto(&target); err != nil {
+		t.Fatalf("DecodeInto failed: %v", err)
+	}
+	if string(target.A) != "[1, 2]" || target.B != 2 {
+		t.Errorf("DecodeInto = %q, %d; want \"[1, 2]\", 2", target.A, target.B)
+	}
+	data, err := Marshal(target)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != `{"A":[1, 2],"B":2}` {
+		t.Errorf("Marshal = %s", data)
+	}
+}
+
+func TestDecodeDuplicateKeyPolicy(t *testing.T) {
+	tests := []struct {
+		policy DuplicateKeyPolicy
+		want   interface{}
+	}{
+		{DUPLICATE_KEY_FIRST_WINS, int64(1)},
+		{DUPLICATE_KEY_LAST_WINS, int64(2)},
+		{DUPLICATE_KEY_ERROR, nil},
+	}
+	for _, test := range tests {
+		d := NewDecoder(strings.NewReader(`{"a":1,"a":2}`))
+		d.DuplicateKeyPolicy = test.policy
+		value, err := d.Decode()
+		if test.want == nil {
+			if err == nil || !strings.Contains(err.Error(), "duplicate key a") {
+				t.Errorf("Decode with policy %d = %v, %v; want a duplicate key error", test.policy, value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Decode with policy %d failed: %v", test.policy, err)
+		} else if a := value.(map[string]interface{})["a"]; a != test.want {
+			t.Errorf("Decode with policy %d kept %v; want %v", test.policy, a, test.want)
+		}
+	}
+}
+
+func TestDecodeCleanEndOfStream(t *testing.T) {
+	d := NewDecoder(strings.NewReader("1 [2]\n  \t"))
+	for i := 0; i < 2; i++ {
+		if _, err := d.Decode(); err != nil {
+			t.Fatalf("Decode of value %d failed: %v", i, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := d.Decode(); err != io.EOF {
+			t.Errorf("Decode at the end of the stream = %v; want io.EOF itself", err)
+		}
+	}
+	var value int
+	if err := NewDecoder(strings.NewReader(" ")).DecodeInto(&value); err != io.EOF {
+		t.Errorf("DecodeInto of empty input = %v; want io.EOF itself", err)
+	}
+	d = NewDecoder(strings.NewReader(`{"a": 1} {"a": `))
+	if _, err := d.Decode(); err != nil {
+		t.Fatalf("Decode of the first value failed: %v", err)
+	}
+	if _, err := d.Decode(); err == io.EOF || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Decode of a truncated value = %v; want an error wrapping io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecodeNegativeZeroAsFloat64(t *testing.T) {
+	for _, input := range []string{"-0", "[-0,-0]", `{"a":-0}`} {
+		d := NewDecoder(strings.NewReader(input))
+		d.NumberMode = NUMBER_MODE_FLOAT64
+		value, err := d.Decode()
+		if err != nil {
+			t.Fatalf("Decode(%q) failed: %v", input, err)
+		}
+		var zeros []interface{}
+		switch value := value.(type) {
+		case []interface{}:
+			zeros = value
+		case map[string]interface{}:
+			zeros = []interface{}{value["a"]}
+		default:
+			zeros = []interface{}{value}
+		}
+		for _, zero := range zeros {
+			if zero, ok := zero.(float64); !ok || zero != 0 || !math.Signbit(zero) {
+				t.Errorf("Decode(%q) gave %#v; want a negative zero float64", input, zero)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Encoder writes JSON values to an output stream.
+type Encoder struct {
+	writer *bufio.Writer
+	// Encode marshals into scratch first so that a value that fails to marshal writes nothing
+	scratch       bytes.Buffer
+	scratchWriter *bufio.Writer
+	// EscapeUnicode makes strings escape every non-ASCII rune as \uXXXX so the output is pure ASCII.
+	EscapeUnicode bool
+	// NonFiniteAsNull writes NaN and infinite floats as null instead of failing, since JSON cannot represent them.
+	NonFiniteAsNull bool
+	// SortKeys writes map members in lexicographic key order instead of Go's random map order. It only applies to
+	// maps: OrderedObject members keep their insertion order and struct fields keep their declaration order.
+	SortKeys bool
+	// NilSliceAsNull writes nil slices, including nil []byte, as null. By default they are written like empty slices,
+	// as [] (or "" for []byte), which differs from encoding/json.
+	NilSliceAsNull bool
+	// NilMapAsNull writes nil maps as null. By default they are written like empty maps, as {}, which differs from
+	// encoding/json.
+	NilMapAsNull bool
+	// FloatPrecision is the number of digits written after the decimal point of floats, or -1, the default, for the
+	// fewest digits that parse back to the same value. A fixed precision rounds, so the number written may not decode
+	// to the float that was marshaled, and a precision of 0 writes integral floats without a fraction.
+	FloatPrecision int
+
+	// Escape the characters that are unsafe when the output is embedded in HTML or JavaScript
+	escapeHTML bool
+	indented   bool
+	prefix     string
+	indent     string
+	depth      int
+	// Set by MarshalCanonical to sort every object and normalize numbers
+	canonical bool
+	// Set by SetNumberFormatter
+	numberFormatter func(interface{}) (string, error)
+	// Set by RegisterTypeEncoder
+	typeEncoders map[reflect.Type]func(interface{}, *bufio.Writer) error
+	// Number of pointers being followed, and their addresses once there are enough to suspect a cycle
+	pointerLevel int
+	pointersSeen map[uintptr]bool
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return newEncoder(bufio.NewWriter(w))
+}
+
+func newEncoder(writer *bufio.Writer) *Encoder {
+	return &Encoder{writer: writer, escapeHTML: true, FloatPrecision: -1}
+}
+
+// Reset discards any unflushed output and makes the Encoder write to w, keeping its options. This lets a single
+// Encoder be reused across outputs without allocating a new buffer.
+func (e *Encoder) Reset(w io.Writer) {
+	e.writer.Reset(w)
+	e.depth = 0
+}
+
+// SetEscapeHTML chooses whether strings escape <, >, and & as \u003c, \u003e, and \u0026, and U+2028 and U+2029 as
+// \u2028 and \u2029, so the output can be embedded in HTML and JavaScript safely. Escaping is on by default.
+func (e *Encoder) SetEscapeHTML(on bool) {
+	e.escapeHTML = on
+}
+
+// SetNumberFormatter makes the Encoder write integers and floats as formatter returns them instead of using its default
+// formatting. The result must be a valid JSON number literal, and formatter sees every number, including NaN and
+// infinities, before NonFiniteAsNull applies. Number values are written as they are. A nil formatter restores the
+// default.
+func (e *Encoder) SetNumberFormatter(formatter func(interface{}) (string, error)) {
+	e.numberFormatter = formatter
+}
+
+// RegisterTypeEncoder makes the Encoder write values of type t by calling fn, which must write a single JSON value to
+// the writer it is given. It takes precedence over every other way of marshaling t, including Marshaler, so it can
+// change the encoding of types the caller does not own, such as time.Duration. A nil fn removes the registration.
+func (e *Encoder) RegisterTypeEncoder(t reflect.Type, fn func(interface{}, *bufio.Writer) error) {
+	if fn == nil {
+		delete(e.typeEncoders, t)
+		return
+	}
+	if e.typeEncoders == nil {
+		e.typeEncoders = make(map[reflect.Type]func(interface{}, *bufio.Writer) error)
+	}
+	e.typeEncoders[t] = fn
+}
+
+// SetIndent makes the Encoder start each new line with prefix followed by one copy of indent per nesting level.
+// Empty arrays and objects are still written as [] and {} on a single line.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.indented = true
+	e.prefix = prefix
+	e.indent = indent
+}
+
+// Encode writes the JSON encoding of value and flushes it to the output. If value fails to marshal, nothing is written
+// and the Encoder can go on to encode other values.
+func (e *Encoder) Encode(value interface{}) error {
+	e.scratch.Reset()
+	if e.scratchWriter == nil {
+		e.scratchWriter = bufio.NewWriter(&e.scratch)
+	}
+	writer := e.writer
+	e.writer = e.scratchWriter
+	err := e.marshalValue(value)
+	if err == nil {
+		err = e.writer.Flush()
+	}
+	e.writer = writer
+	if err != nil {
+		// Drop the partial value, and the nesting it was left at
+		e.scratchWriter.Reset(&e.scratch)
+		e.depth = 0
+		return err
+	}
+	if _, err := e.writer.Write(e.scratch.Bytes()); err != nil {
+		return fmt.Errorf("failed to write value: %w", err)
+	}
+	if err := e.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncoderReset(t *testing.T) {
+	var first, second bytes.Buffer
+	e := NewEncoder(&first)
+	e.SortKeys = true
+	if err := e.Encode(map[string]int{"b": 1, "a": 2}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	e.Reset(&second)
+	if err := e.Encode(map[string]int{"d": 3, "c": 4}); err != nil {
+		t.Fatalf("Encode after Reset failed: %v", err)
+	}
+	if first.String() != `{"a":2,"b":1}` || second.String() != `{"c":4,"d":3}` {
+		t.Errorf("Encode wrote %q and %q", first.String(), second.String())
+	}
+}
+
+func TestEncodeFailureWritesNothing(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	if err := e.Encode([]interface{}{1, "two", make(chan int)}); err == nil {
+		t.Fatal("Encode accepted a channel")
+	}
+	if err := MarshalTo([]interface{}{1, make(chan int)}, &buf); err == nil {
+		t.Fatal("MarshalTo accepted a channel")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("failed Encode and MarshalTo wrote %q", buf.String())
+	}
+	if err := e.Encode([]int{1}); err != nil || buf.String() != "[1]" {
+		t.Errorf("Encode after a failure wrote %q, %v; want [1]", buf.String(), err)
+	}
+}
+
+func TestEncoderRegisterTypeEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SortKeys = true
+	e.RegisterTypeEncoder(reflect.TypeOf(time.Duration(0)), func(value interface{}, writer *bufio.Writer) error {
+		return MarshalString(value.(time.Duration).String(), writer)
+	})
+	value := map[string]interface{}{"timeout": 90 * time.Second, "retries": []time.Duration{time.Millisecond}}
+	if err := e.Encode(value); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if buf.String() != `{"retries":["1ms"],"timeout":"1m30s"}` {
+		t.Errorf("Encode = %s", buf.String())
+	}
+}
+
+func TestEncoderFloatPrecision(t *testing.T) {
+	if precision := NewEncoder(io.Discard).FloatPrecision; precision != -1 {
+		t.Errorf("FloatPrecision defaults to %d; want -1", precision)
+	}
+	tests := []struct {
+		precision int
+		want      string
+	}{
+		{-1, `[3.14159,2.0,0.1,1e+21]`},
+		{2, `[3.14,2.00,0.10,1000000000000000000000.00]`},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		e.FloatPrecision = test.precision
+		if err := e.Encode([]float64{3.14159, 2, 0.1, 1e21}); err != nil {
+			t.Fatalf("Encode with FloatPrecision %d failed: %v", test.precision, err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("Encode with FloatPrecision %d = %s; want %s", test.precision, buf.String(), test.want)
+		}
+	}
+}
+
+func TestMarshalToDoesNotPoolLargeBuffers(t *testing.T) {
+	large := strings.Repeat("x", 4*MAX_POOLED_BUFFER_SIZE)
+	for i := 0; i < 10; i++ {
+		if err := MarshalTo(large, io.Discard); err != nil {
+			t.Fatalf("MarshalTo failed: %v", err)
+		}
+		m := marshalPool.Get().(*pooledMarshaler)
+		if m.buf.Cap() > MAX_POOLED_BUFFER_SIZE || m.encoder.scratch.Cap() > MAX_POOLED_BUFFER_SIZE {
+			t.Fatalf("pooled marshaler kept buffers of %d and %d bytes", m.buf.Cap(), m.encoder.scratch.Cap())
+		}
+		marshalPool.Put(m)
+	}
+}
+
+func TestEncoderEscapeHTML(t *testing.T) {
+	value := map[string]string{"<key>": "<script>alert(1 && 2)</script>"}
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `{"\u003ckey\u003e":"\u003cscript\u003ealert(1 \u0026\u0026 2)\u003c/script\u003e"}`
+	if string(data) != want {
+		t.Errorf("Marshal = %s; want %s", data, want)
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(value); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := `{"<key>":"<script>alert(1 && 2)</script>"}`; buf.String() != want {
+		t.Errorf("Encode without HTML escaping = %s; want %s", buf.String(), want)
+	}
+}
+
+type testCollections struct {
+	List  []int
+	Map   map[string]int
+	Bytes []byte
+}
+
+func TestEncoderNilCollections(t *testing.T) {
+	empty := testCollections{[]int{}, map[string]int{}, []byte{}}
+	tests := []struct {
+		sliceAsNull, mapAsNull bool
+		value                  interface{}
+		want                   string
+	}{
+		{false, false, []int(nil), `[]`},
+		{false, false, map[string]int(nil), `{}`},
+		{false, false, testCollections{}, `{"List":[],"Map":{},"Bytes":""}`},
+		{true, false, []int(nil), `null`},
+		{true, false, map[string]int(nil), `{}`},
+		{true, false, testCollections{}, `{"List":null,"Map":{},"Bytes":null}`},
+		{false, true, []int(nil), `[]`},
+		{false, true, map[string]int(nil), `null`},
+		{false, true, testCollections{}, `{"List":[],"Map":null,"Bytes":""}`},
+		{true, true, empty, `{"List":[],"Map":{},"Bytes":""}`},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		e.NilSliceAsNull = test.sliceAsNull
+		e.NilMapAsNull = test.mapAsNull
+		if err := e.Encode(test.value); err != nil {
+			t.Fatalf("Encode(%#v) failed: %v", test.value, err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("Encode(%#v) with NilSliceAsNull %t and NilMapAsNull %t = %s; want %s", test.value,
+				test.sliceAsNull, test.mapAsNull, buf.String(), test.want)
+		}
+	}
+}
+
+var benchmarkRecord = map[string]interface{}{
+	"id":      int64(12345),
+	"name":    "example",
+	"tags":    []interface{}{"a", "b", "c"},
+	"score":   98.5,
+	"enabled": true,
+}
+
+// BenchmarkMarshal uses the pooled encoder behind Marshal.
+func BenchmarkMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(benchmarkRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEncoderReset reuses one Encoder across outputs.
+func BenchmarkEncoderReset(b *testing.B) {
+	b.ReportAllocs()
+	e := NewEncoder(io.Discard)
+	for i := 0; i < b.N; i++ {
+		e.Reset(io.Discard)
+		if err := e.Encode(benchmarkRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkNewEncoder allocates an Encoder per output, for comparison with BenchmarkEncoderReset.
+func BenchmarkNewEncoder(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := NewEncoder(io.Discard).Encode(benchmarkRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
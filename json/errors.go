@@ -0,0 +1,94 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError describes where parsing failed. Offset is the number of bytes consumed before the error, and Line and
+// Column locate the last rune consumed. Err is the underlying error, so errors.Is and errors.As see through it.
+type SyntaxError struct {
+	Offset int64
+	Line   int
+	Column int
+	Msg    string
+	Err    error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("parse error at line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedTypeError is returned when marshaling a value of a type JSON cannot represent, such as a channel, func,
+// or complex number.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("cannot marshal value of unsupported type %s", e.Type)
+}
+
+// UnsupportedValueError is returned when marshaling a value that JSON cannot represent although its type is
+// supported, such as a pointer cycle. It carries no PathError, as the path around a cycle repeats until it is detected.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return fmt.Sprintf("cannot marshal value of type %s, %s", e.Value.Type(), e.Str)
+}
+
+// PathError records where in the document an error happened. Elements holds the object keys (as strings) and array
+// indices (as ints) leading from the top-level value to the value that failed to parse.
+type PathError struct {
+	Elements []interface{}
+	Err      error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("at %s: %s", e.Path(), e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// Path returns Elements as a JSONPath expression such as $.users[3].address.zip. Keys that are not identifiers are
+// written as quoted strings in brackets, as in $["first name"].
+func (e *PathError) Path() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, element := range e.Elements {
+		switch element := element.(type) {
+		case int:
+			b.WriteString("[" + strconv.Itoa(element) + "]")
+		case string:
+			if isIdentifier(element) {
+				b.WriteString("." + element)
+			} else {
+				b.WriteString("[" + strconv.Quote(element) + "]")
+			}
+		}
+	}
+	return b.String()
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || c == '$' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (i > 0 && '0' <= c && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
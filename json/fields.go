@@ -0,0 +1,212 @@
+package json
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structField is an exported struct field as seen by JSON, named by its json tag when it has one. Fields promoted
+// from embedded structs have an index path longer than one.
+type structField struct {
+	name   string
+	index  []int
+	typ    reflect.Type
+	tagged bool
+}
+
+// typeFields is what JSON needs to know about a struct type, worked out once per type.
+type typeFields struct {
+	fields []structField
+	// Index in fields of each field name
+	byName       map[string]int
+	remaining    structField
+	hasRemaining bool
+}
+
+// Cached *typeFields of each struct type
+var fieldCache sync.Map
+
+func cachedTypeFields(structType reflect.Type) *typeFields {
+	if cached, ok := fieldCache.Load(structType); ok {
+		return cached.(*typeFields)
+	}
+	fields := &typeFields{fields: collectStructFields(structType), byName: make(map[string]int)}
+	for i, field := range fields.fields {
+		fields.byName[field.name] = i
+	}
+	fields.remaining, fields.hasRemaining = findRemainingField(structType)
+	cached, _ := fieldCache.LoadOrStore(structType, fields)
+	return cached.(*typeFields)
+}
+
+// structFields returns the fields of structType that take part in JSON, in declaration order. Unexported fields and
+// fields tagged json:"-" or with the remaining option are left out. The exported fields of untagged embedded structs,
+// exported or not, are promoted into the parent, and when several fields share a name the shallowest one wins, then a
+// tagged one; if that still leaves a tie the name is dropped, as in encoding/json.
+func structFields(structType reflect.Type) []structField {
+	return cachedTypeFields(structType).fields
+}
+
+func collectStructFields(structType reflect.Type) []structField {
+	var candidates []structField
+	collectFields(structType, nil, map[reflect.Type]bool{}, &candidates)
+
+	byName := make(map[string][]structField)
+	for _, field := range candidates {
+		byName[field.name] = append(byName[field.name], field)
+	}
+	var fields []structField
+	for _, field := range candidates {
+		if dominant, ok := dominantField(byName[field.name]); ok && sameIndex(dominant.index, field.index) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// collectFields appends every candidate field of structType to fields, descending into embedded structs. visiting
+// holds the struct types on the current path so that embedded pointer cycles terminate.
+func collectFields(structType reflect.Type, parent []int, visiting map[reflect.Type]bool, fields *[]structField) {
+	visiting[structType] = true
+	defer delete(visiting, structType)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if hasTagOption(options, "remaining") {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				// The exported fields of an unexported embedded struct are promoted too, as in encoding/json
+				if !visiting[embedded] {
+					collectFields(embedded, index, visiting, fields)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		*fields = append(*fields, structField{name: name, index: index, typ: field.Type, tagged: tagged})
+	}
+}
+
+// remainingField returns the field of structType tagged with the remaining option, such as json:",remaining", which
+// collects the object members that match no other field. It must be a map with string-kinded keys.
+func remainingField(structType reflect.Type) (structField, bool) {
+	fields := cachedTypeFields(structType)
+	return fields.remaining, fields.hasRemaining
+}
+
+func findRemainingField(structType reflect.Type) (structField, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if _, options, _ := strings.Cut(field.Tag.Get("json"), ","); hasTagOption(options, "remaining") {
+			return structField{name: field.Name, index: field.Index, typ: field.Type}, true
+		}
+	}
+	return structField{}, false
+}
+
+// hasTagOption reports whether the comma-separated options of a json tag include option.
+func hasTagOption(options string, option string) bool {
+	for options != "" {
+		var current string
+		current, options, _ = strings.Cut(options, ",")
+		if current == option {
+			return true
+		}
+	}
+	return false
+}
+
+// dominantField picks the field that wins among fields sharing one name, reporting false when none does.
+func dominantField(fields []structField) (structField, bool) {
+	depth := len(fields[0].index)
+	for _, field := range fields[1:] {
+		if len(field.index) < depth {
+			depth = len(field.index)
+		}
+	}
+	var shallowest []structField
+	for _, field := range fields {
+		if len(field.index) == depth {
+			shallowest = append(shallowest, field)
+		}
+	}
+	if len(shallowest) == 1 {
+		return shallowest[0], true
+	}
+	var tagged []structField
+	for _, field := range shallowest {
+		if field.tagged {
+			tagged = append(tagged, field)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return structField{}, false
+}
+
+func sameIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldByIndex walks index from object the way reflect.Value.FieldByIndex does. Nil embedded pointers are allocated
+// when allocate is set, except for pointers to unexported types, which reflect cannot set; otherwise fieldByIndex
+// reports false on reaching one.
+func fieldByIndex(object reflect.Value, index []int, allocate bool) (reflect.Value, bool) {
+	for i, position := range index {
+		if i > 0 && object.Kind() == reflect.Pointer {
+			if object.IsNil() {
+				if !allocate || !object.CanSet() {
+					return reflect.Value{}, false
+				}
+				object.Set(reflect.New(object.Type().Elem()))
+			}
+			object = object.Elem()
+		}
+		object = object.Field(position)
+	}
+	return object, true
+}
+
+// findField returns the field of structType named key, preferring an exact match over a case-insensitive one.
+func findField(structType reflect.Type, key string) (structField, bool) {
+	fields := cachedTypeFields(structType)
+	if i, ok := fields.byName[key]; ok {
+		return fields.fields[i], true
+	}
+	for _, field := range fields.fields {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return structField{}, false
+}
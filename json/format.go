@@ -0,0 +1,107 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Compact copies the JSON value in src to dst without insignificant whitespace. Strings and numbers are copied
+// exactly as written, and malformed input is an error.
+func Compact(dst *bufio.Writer, src *bufio.Reader) error {
+	d := newDecoder(src)
+	return d.wrapError(d.reformat(dst, false, "", ""))
+}
+
+// Indent copies the JSON value in src to dst, starting each new line with prefix followed by one copy of indent per
+// nesting level. Key order, strings, and numbers are copied exactly as written. Empty arrays and objects are written as
+// [] and {} on a single line.
+func Indent(dst *bufio.Writer, src *bufio.Reader, prefix, indent string) error {
+	d := newDecoder(src)
+	return d.wrapError(d.reformat(dst, true, prefix, indent))
+}
+
+// reformat copies a single value token by token to writer, failing on anything after it but whitespace.
+func (d *Decoder) reformat(writer *bufio.Writer, indented bool, prefix, indent string) error {
+	newline := func(depth int) error {
+		if !indented {
+			return nil
+		}
+		if err := writer.WriteByte('\n'); err != nil {
+			return fmt.Errorf("failed to write newline: %w", err)
+		}
+		if _, err := writer.WriteString(prefix); err != nil {
+			return fmt.Errorf("failed to write prefix: %w", err)
+		}
+		for i := 0; i < depth; i++ {
+			if _, err := writer.WriteString(indent); err != nil {
+				return fmt.Errorf("failed to write indent: %w", err)
+			}
+		}
+		return nil
+	}
+	d.UseNumber = true
+	for {
+		prevState := d.tokenState
+		depth := len(d.tokenStack)
+		token, raw, err := d.rawToken()
+		if err == io.EOF {
+			return EMPTY_INPUT
+		} else if err != nil {
+			return err
+		}
+		delim, isDelim := token.(Delim)
+		isClose := isDelim && (delim == ']' || delim == '}')
+		if isClose {
+			// Empty arrays and objects stay on one line
+			if prevState != 1 && prevState != 4 {
+				if err := newline(depth - 1); err != nil {
+					return err
+				}
+			}
+		} else if prevState == 3 || prevState == 8 {
+			if err := writer.WriteByte(','); err != nil {
+				return fmt.Errorf("failed to write ,: %w", err)
+			}
+			if err := newline(depth); err != nil {
+				return err
+			}
+		} else if prevState == 1 || prevState == 4 {
+			if err := newline(depth); err != nil {
+				return err
+			}
+		} else if prevState == 6 {
+			if err := writer.WriteByte(':'); err != nil {
+				return fmt.Errorf("failed to write ':': %w", err)
+			}
+			if indented {
+				if err := writer.WriteByte(' '); err != nil {
+					return fmt.Errorf("failed to write ' ': %w", err)
+				}
+			}
+		}
+		if _, err := writer.Write(raw); err != nil {
+			return fmt.Errorf("failed to write token %s: %w", raw, err)
+		}
+		if len(d.tokenStack) == 0 {
+			break
+		}
+	}
+	return d.expectEOF()
+}
+
+// rawToken returns the next token along with its bytes exactly as they appear in the input.
+func (d *Decoder) rawToken() (Token, []byte, error) {
+	d.reader.startCapture()
+	token, err := d.token()
+	raw := d.reader.stopCapture()
+	if err != nil {
+		return nil, nil, err
+	}
+	// The capture also holds the whitespace and separators around the token
+	raw = bytes.TrimPrefix(raw, []byte(string(BYTE_ORDER_MARK)))
+	raw = bytes.TrimLeft(raw, " \t\r\n,:")
+	raw = bytes.TrimRight(raw, WHITESPACE_RUNES)
+	return token, raw, nil
+}
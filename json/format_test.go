@@ -0,0 +1,28 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatEmptyInput(t *testing.T) {
+	for _, input := range []string{"", "  \n\t"} {
+		var buf bytes.Buffer
+		writer := bufio.NewWriter(&buf)
+		if err := Compact(writer, bufio.NewReader(strings.NewReader(input))); !errors.Is(err, EMPTY_INPUT) {
+			t.Errorf("Compact(%q) = %v; want EMPTY_INPUT", input, err)
+		}
+		if err := Indent(writer, bufio.NewReader(strings.NewReader(input)), "", "  "); !errors.Is(err, EMPTY_INPUT) {
+			t.Errorf("Indent(%q) = %v; want EMPTY_INPUT", input, err)
+		}
+		if err := ValidError([]byte(input)); !errors.Is(err, EMPTY_INPUT) {
+			t.Errorf("ValidError(%q) = %v; want EMPTY_INPUT", input, err)
+		}
+		if _, err := Unmarshal([]byte(input)); !errors.Is(err, EMPTY_INPUT) {
+			t.Errorf("Unmarshal(%q) = %v; want EMPTY_INPUT", input, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var fuzzSeeds = []string{
+	`{"a":[1,2.5,-3e2,"xé😀"],"b":null,"c":true}`,
+	`[]`,
+	`{}`,
+	` [ {} , [ ] ] `,
+	`-0.0`,
+	`0.1e-5`,
+	`1e400`,
+	`-9223372036854775808`,
+	`9223372036854775808`,
+	`01`,
+	`"\u12"`,
+	`"\ud800"`,
+	`"😀"`,
+	`[1,{"a":"\\"}]`,
+	"\"\xff\"",
+	"\"a\nb\"",
+	`tru`,
+	`{"a" 1}`,
+	` [1 , 2 ] x`,
+}
+
+// FuzzUnmarshal checks that arbitrary input is parsed or rejected without panicking.
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, err := UnmarshalValue(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil && value != nil {
+			t.Fatalf("UnmarshalValue(%q) returned %#v along with error %v", data, value, err)
+		}
+		if _, err := Unmarshal(data); err == nil && !Valid(data) {
+			t.Fatalf("Unmarshal accepted %q but Valid rejected it", data)
+		}
+	})
+}
+
+// FuzzRoundTrip checks that every value parsed from arbitrary input marshals and parses back to the same value.
+func FuzzRoundTrip(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, err := Unmarshal(data)
+		if err != nil {
+			return
+		}
+		out, err := Marshal(value)
+		if err != nil {
+			t.Fatalf("Marshal(%#v) from %q failed: %v", value, data, err)
+		}
+		parsed, err := Unmarshal(out)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q), marshaled from %q, failed: %v", out, data, err)
+		}
+		if !reflect.DeepEqual(value, parsed) {
+			t.Fatalf("round trip of %q through %q gave %#v; want %#v", data, out, parsed, value)
+		}
+	})
+}
+
+// FuzzUnmarshalBytes checks that parsing a byte slice in place agrees with parsing it through a bufio.Reader.
+func FuzzUnmarshalBytes(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fromBytes, bytesErr := UnmarshalBytes(data)
+		fromReader, readerErr := UnmarshalComplete(bufio.NewReader(bytes.NewReader(data)))
+		if !reflect.DeepEqual(fromBytes, fromReader) {
+			t.Fatalf("%q parsed as %#v from bytes but %#v from a reader", data, fromBytes, fromReader)
+		}
+		if (bytesErr == nil) != (readerErr == nil) || bytesErr != nil && bytesErr.Error() != readerErr.Error() {
+			t.Fatalf("%q failed with %v from bytes but %v from a reader", data, bytesErr, readerErr)
+		}
+	})
+}
@@ -2,122 +2,380 @@ package json
 
 import (
 	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/base64"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
+// Marshaler is implemented by types that write their own JSON encoding. MarshalValue calls MarshalJSONValue
+// instead of encoding the value through reflection.
+type Marshaler interface {
+	MarshalJSONValue(writer *bufio.Writer) error
+}
+
+// Marshal returns the JSON encoding of value.
+func Marshal(value interface{}) ([]byte, error) {
+	m := marshalPool.Get().(*pooledMarshaler)
+	defer m.release()
+	if err := m.encoder.marshalValue(value); err != nil {
+		return nil, err
+	}
+	if err := m.encoder.writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to flush writer: %w", err)
+	}
+	// The buffer goes back to the pool, so the caller gets a copy
+	return append([]byte(nil), m.buf.Bytes()...), nil
+}
+
+// MarshalTo writes the JSON encoding of value to w, which needs no buffering of its own. Output is buffered internally
+// and flushed before MarshalTo returns. If marshaling fails, nothing is written to w.
+func MarshalTo(value interface{}, w io.Writer) error {
+	m := marshalPool.Get().(*pooledMarshaler)
+	defer m.release()
+	m.encoder.Reset(w)
+	return m.encoder.Encode(value)
+}
+
+// Buffers larger than this are dropped instead of pooled so one huge value does not pin its memory forever
+const MAX_POOLED_BUFFER_SIZE = 64 * 1024
+
+// pooledMarshaler is an Encoder and its output buffer, reused across calls to Marshal.
+type pooledMarshaler struct {
+	encoder *Encoder
+	buf     bytes.Buffer
+}
+
+var marshalPool = sync.Pool{
+	New: func() interface{} {
+		m := &pooledMarshaler{}
+		m.encoder = NewEncoder(&m.buf)
+		return m
+	},
+}
+
+func (m *pooledMarshaler) release() {
+	// MarshalTo goes through Encode, which buffers the whole value in the encoder's scratch buffer instead of buf
+	if m.buf.Cap() > MAX_POOLED_BUFFER_SIZE || m.encoder.scratch.Cap() > MAX_POOLED_BUFFER_SIZE {
+		return
+	}
+	m.buf.Reset()
+	m.encoder.Reset(&m.buf)
+	marshalPool.Put(m)
+}
+
+// MarshalIndent is like MarshalValue but starts each new line with prefix followed by one copy of indent per nesting level.
+// Empty arrays and objects stay on one line as [] and {}.
+func MarshalIndent(value interface{}, writer *bufio.Writer, prefix, indent string) error {
+	e := newEncoder(writer)
+	e.SetIndent(prefix, indent)
+	return e.marshalValue(value)
+}
+
 func MarshalValue(value interface{}, writer *bufio.Writer) error {
+	return newEncoder(writer).marshalValue(value)
+}
+
+func (e *Encoder) marshalValue(value interface{}) error {
 	// Handle null value
 	if value == nil {
-		if err := MarshalNull(writer); err != nil {
+		if err := e.marshalNull(); err != nil {
 			return fmt.Errorf("failed to write null: %w", err)
 		}
 		return nil
 	}
+	if typeEncoder, ok := e.typeEncoders[reflect.TypeOf(value)]; ok {
+		if err := typeEncoder(value, e.writer); err != nil {
+			return fmt.Errorf("failed to marshal %T with its registered encoder: %w", value, err)
+		}
+		return nil
+	}
+	if marshaler, ok := value.(Marshaler); ok {
+		if reflectedValue := reflect.ValueOf(marshaler); reflectedValue.Kind() == reflect.Pointer && reflectedValue.IsNil() {
+			return e.marshalNull()
+		}
+		if e.canonical {
+			return e.marshalCanonicalMarshaler(marshaler)
+		}
+		if err := marshaler.MarshalJSONValue(e.writer); err != nil {
+			return fmt.Errorf("failed to marshal %T with MarshalJSONValue: %w", value, err)
+		}
+		return nil
+	}
+	if t, ok := value.(time.Time); ok {
+		return e.marshalString(t.Format(time.RFC3339Nano))
+	}
+	if textMarshaler, ok := value.(encoding.TextMarshaler); ok {
+		if reflectedValue := reflect.ValueOf(textMarshaler); reflectedValue.Kind() == reflect.Pointer && reflectedValue.IsNil() {
+			return e.marshalNull()
+		}
+		text, err := textMarshaler.MarshalText()
+		if err != nil {
+			return fmt.Errorf("failed to marshal %T with MarshalText: %w", value, err)
+		}
+		return e.marshalString(string(text))
+	}
+	// Errors are written as their message unless they choose their own encoding above
+	if err, ok := value.(error); ok {
+		if reflectedValue := reflect.ValueOf(err); reflectedValue.Kind() == reflect.Pointer && reflectedValue.IsNil() {
+			return e.marshalNull()
+		}
+		return e.marshalString(err.Error())
+	}
+	if object, ok := value.(*OrderedObject); ok {
+		if object == nil {
+			return e.marshalNull()
+		}
+		return e.marshalOrderedObject(object)
+	}
+	if keyValues, ok := value.([]KeyValue); ok {
+		return e.marshalKeyValues(keyValues)
+	}
+	if number, ok := value.(Number); ok {
+		return e.marshalLiteralNumber(number)
+	}
 	// Handle non-null values
 	valueType := reflect.TypeOf(value)
 	switch valueType.Kind() {
 	case reflect.String:
-		valueString, ok := value.(string)
-		if !ok {
-			return fmt.Errorf("failed to cast value to string")
-		}
-		return MarshalString(valueString, writer)
-	case reflect.Int64:
-		fallthrough
-	case reflect.Float64:
-		return MarshalNumber(value, writer)
+		// Read through reflect so that named string types such as type Color string are included
+		return e.marshalString(reflect.ValueOf(value).String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return e.marshalNumber(value)
 	case reflect.Map:
-		object, ok := value.(map[string]interface{})
-		if !ok {
-			return fmt.Errorf("failed to cast object to map[string]interface{}")
+		if e.NilMapAsNull && reflect.ValueOf(value).IsNil() {
+			return e.marshalNull()
 		}
-		return MarshalObject(object, writer)
-	case reflect.Array:
-		fallthrough
+		return e.marshalMap(reflect.ValueOf(value))
 	case reflect.Slice:
+		if e.NilSliceAsNull && reflect.ValueOf(value).IsNil() {
+			return e.marshalNull()
+		}
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			return e.marshalString(base64.StdEncoding.EncodeToString(reflect.ValueOf(value).Bytes()))
+		}
+		fallthrough
+	case reflect.Array:
 		reflectedValues := reflect.ValueOf(value)
 		values := make([]interface{}, reflectedValues.Len())
 		for i := 0; i < reflectedValues.Len(); i++ {
 			values[i] = reflectedValues.Index(i).Interface()
 		}
-		return MarshalArray(values, writer)
+		return e.marshalArray(values)
+	case reflect.Pointer:
+		return e.marshalPointer(reflect.ValueOf(value))
+	case reflect.Struct:
+		return e.marshalStruct(reflect.ValueOf(value))
 	case reflect.Bool:
-		value, ok := value.(bool)
-		if !ok {
-			return fmt.Errorf("failed to cast bool")
-		}
-		return MarshalBoolean(value, writer)
+		return e.marshalBoolean(reflect.ValueOf(value).Bool())
 	default:
-		return fmt.Errorf("cannot marshal value %v", value)
+		// Channels, funcs, complex numbers, and unsafe pointers
+		return &UnsupportedTypeError{Type: valueType}
 	}
 }
 
 func MarshalString(value string, writer *bufio.Writer) error {
+	return newEncoder(writer).marshalString(value)
+}
+
+func (e *Encoder) marshalString(value string) error {
 	var err error
-	if err = writer.WriteByte('"'); err != nil {
+	if err = e.writer.WriteByte('"'); err != nil {
 		return fmt.Errorf("failed to write opening \" in string %s: %w", value, err)
 	}
-	for _, c := range value {
+	// Write runs of runes that need no escaping in bulk
+	start := 0
+	for i := 0; i < len(value); {
+		c, size := utf8.DecodeRuneInString(value[i:])
+		if !e.needsEscape(c, size) {
+			i += size
+			continue
+		}
+		if _, err = e.writer.WriteString(value[start:i]); err != nil {
+			return fmt.Errorf("failed to write string %s: %w", value, err)
+		}
 		switch c {
 		case '"':
-			_, err = writer.WriteString(`\"`)
+			_, err = e.writer.WriteString(`\"`)
 		case '\\':
-			_, err = writer.WriteString(`\\`)
+			_, err = e.writer.WriteString(`\\`)
 		case '\b':
-			_, err = writer.WriteString(`\b`)
+			_, err = e.writer.WriteString(`\b`)
 		case '\f':
-			_, err = writer.WriteString(`\f`)
+			_, err = e.writer.WriteString(`\f`)
 		case '\n':
-			_, err = writer.WriteString(`\n`)
+			_, err = e.writer.WriteString(`\n`)
 		case '\r':
-			_, err = writer.WriteString(`\r`)
+			_, err = e.writer.WriteString(`\r`)
 		case '\t':
-			_, err = writer.WriteString(`\t`)
+			_, err = e.writer.WriteString(`\t`)
 		default:
-			_, err = writer.WriteRune(c)
+			if c == utf8.RuneError && size == 1 && !e.EscapeUnicode {
+				// Invalid UTF-8 is replaced rather than escaped
+				_, err = e.writer.WriteRune(c)
+			} else {
+				err = e.writeUnicodeEscape(c)
+			}
 		}
 		if err != nil {
 			return fmt.Errorf("failed to write rune %c from string %s: %w", c, value, err)
 		}
+		i += size
+		start = i
+	}
+	if _, err = e.writer.WriteString(value[start:]); err != nil {
+		return fmt.Errorf("failed to write string %s: %w", value, err)
 	}
-	if err = writer.WriteByte('"'); err != nil {
+	if err = e.writer.WriteByte('"'); err != nil {
 		return fmt.Errorf("failed to write closing \" in string %s: %w", value, err)
 	}
 	return nil
 }
 
+// needsEscape reports whether the rune c, which took size bytes in the string, cannot be copied to the output as is.
+// Invalid UTF-8 bytes are rewritten as U+FFFD.
+func (e *Encoder) needsEscape(c rune, size int) bool {
+	switch c {
+	case '"', '\\':
+		return true
+	case '<', '>', '&':
+		return e.escapeHTML
+	case '\u2028', '\u2029':
+		// Line and paragraph separators end a line in JavaScript, breaking JSON embedded in a script
+		return e.escapeHTML
+	}
+	// An invalid byte decodes as utf8.RuneError with size 1, while a U+FFFD in the string has size 3
+	if c < 0x20 || c == utf8.RuneError && size == 1 {
+		return true
+	}
+	return e.EscapeUnicode && c > unicode.MaxASCII
+}
+
+// writeUnicodeEscape writes r as a \uXXXX escape, using a surrogate pair for runes outside the Basic Multilingual Plane.
+func (e *Encoder) writeUnicodeEscape(r rune) error {
+	if r > 0xFFFF {
+		high, low := utf16.EncodeRune(r)
+		if err := e.writeUnicodeEscape(high); err != nil {
+			return err
+		}
+		return e.writeUnicodeEscape(low)
+	}
+	_, err := fmt.Fprintf(e.writer, `\u%04x`, r)
+	return err
+}
+
 func MarshalNumber(value interface{}, writer *bufio.Writer) error {
+	return newEncoder(writer).marshalNumber(value)
+}
+
+func (e *Encoder) marshalNumber(value interface{}) error {
+	if e.numberFormatter != nil {
+		return e.marshalFormattedNumber(value)
+	}
 	var valueString string
-	switch reflect.TypeOf(value).Kind() {
-	case reflect.Int64:
-		valueInt64, ok := value.(int64)
-		if !ok {
-			return fmt.Errorf("failed to cast number to int64")
+	reflectedValue := reflect.ValueOf(value)
+	switch reflectedValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		valueString = strconv.FormatInt(reflectedValue.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		valueString = strconv.FormatUint(reflectedValue.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		valueFloat64 := reflectedValue.Float()
+		if math.IsNaN(valueFloat64) || math.IsInf(valueFloat64, 0) {
+			if e.NonFiniteAsNull {
+				return e.marshalNull()
+			}
+			return fmt.Errorf("cannot marshal non-finite number %v", valueFloat64)
 		}
-		valueString = strconv.FormatInt(valueInt64, 10)
-	case reflect.Float64:
-		valueFloat64, ok := value.(float64)
-		if !ok {
-			return fmt.Errorf("failed to cast number to float64")
+		if e.canonical {
+			valueString = formatCanonicalFloat(valueFloat64)
+		} else {
+			valueString = formatFloat(valueFloat64, e.FloatPrecision, reflectedValue.Type().Bits())
+			// Keep integral floats looking like floats so they decode back as float64 rather than int64
+			if e.FloatPrecision < 0 && !strings.ContainsAny(valueString, ".e") {
+				valueString += ".0"
+			}
 		}
-		valueString = strconv.FormatFloat(valueFloat64, 'f', -1, 64)
 	default:
-		return fmt.Errorf("number was not int64 or float64")
+		return fmt.Errorf("number was not an integer or float kind")
+	}
+	if _, err := e.writer.WriteString(valueString); err != nil {
+		return fmt.Errorf("failed to write value %s: %w", valueString, err)
+	}
+	return nil
+}
+
+func (e *Encoder) marshalFormattedNumber(value interface{}) error {
+	valueString, err := e.numberFormatter(value)
+	if err != nil {
+		return fmt.Errorf("failed to format number %v: %w", value, err)
+	}
+	if !isValidNumber(valueString) {
+		return fmt.Errorf("number formatter returned invalid number literal %q for %v", valueString, value)
 	}
-	if _, err := writer.WriteString(valueString); err != nil {
+	if _, err := e.writer.WriteString(valueString); err != nil {
 		return fmt.Errorf("failed to write value %s: %w", valueString, err)
 	}
 	return nil
 }
 
+// formatFloat formats a finite float the way encoding/json does: the shortest decimal that parses back to the same
+// value of the given bit size, in exponent form for magnitudes below 1e-6 or from 1e21 up. A precision of 0 or more
+// writes that many digits after the decimal point instead, never in exponent form.
+func formatFloat(value float64, precision int, bits int) string {
+	format := byte('f')
+	if abs := math.Abs(value); abs != 0 && precision < 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	formatted := strconv.FormatFloat(value, format, precision, bits)
+	if format == 'e' {
+		// Drop the padding zero Go puts in one digit negative exponents, turning 1e-07 into 1e-7
+		n := len(formatted)
+		if n >= 4 && formatted[n-4] == 'e' && formatted[n-3] == '-' && formatted[n-2] == '0' {
+			formatted = formatted[:n-2] + formatted[n-1:]
+		}
+	}
+	return formatted
+}
+
+func (e *Encoder) marshalLiteralNumber(number Number) error {
+	if !isValidNumber(string(number)) {
+		return fmt.Errorf("invalid number literal %q", number)
+	}
+	if e.canonical {
+		return e.marshalCanonicalNumber(number)
+	}
+	if _, err := e.writer.WriteString(string(number)); err != nil {
+		return fmt.Errorf("failed to write value %s: %w", number, err)
+	}
+	return nil
+}
+
 func MarshalBoolean(value bool, writer *bufio.Writer) error {
+	return newEncoder(writer).marshalBoolean(value)
+}
+
+func (e *Encoder) marshalBoolean(value bool) error {
 	var err error
 	if value {
-		_, err = writer.WriteString(TRUE_STRING)
+		_, err = e.writer.WriteString(TRUE_STRING)
 	} else {
-		_, err = writer.WriteString(FALSE_STRING)
+		_, err = e.writer.WriteString(FALSE_STRING)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to write boolean: %w", err)
@@ -126,56 +384,292 @@ func MarshalBoolean(value bool, writer *bufio.Writer) error {
 }
 
 func MarshalNull(writer *bufio.Writer) error {
-	if _, err := writer.WriteString(NULL_STRING); err != nil {
+	return newEncoder(writer).marshalNull()
+}
+
+func (e *Encoder) marshalNull() error {
+	if _, err := e.writer.WriteString(NULL_STRING); err != nil {
 		return fmt.Errorf("failed to write null: %w", err)
 	}
 	return nil
 }
 
 func MarshalArray(values []interface{}, writer *bufio.Writer) error {
-	if err := writer.WriteByte('['); err != nil {
-		return fmt.Errorf("failed to write [: %w", err)
+	return newEncoder(writer).marshalArray(values)
+}
+
+func (e *Encoder) marshalArray(values []interface{}) error {
+	if err := e.openCollection('['); err != nil {
+		return err
 	}
+	first := true
 	for i, value := range values {
-		if err := MarshalValue(value, writer); err != nil {
-			return fmt.Errorf("failed to write array value at index %d: %w", i, err)
+		if err := e.nextElement(first); err != nil {
+			return err
 		}
-		if i < len(values)-1 {
-			if err := writer.WriteByte(','); err != nil {
-				return fmt.Errorf("failed to write ,: %w", err)
-			}
+		if err := e.marshalValue(value); err != nil {
+			return withPathElement(i, err)
 		}
+		first = false
+	}
+	return e.closeCollection(']', first)
+}
+
+// withPathElement records that err happened inside the array element or object member that element indexes or names.
+// Errors from nested values already carry the rest of the path, so each enclosing array or object adds its own element
+// in front and the caller gets a single PathError such as "at $.users[2].handler: ...". An UnsupportedValueError is
+// returned as is.
+func withPathElement(element interface{}, err error) error {
+	if _, ok := err.(*UnsupportedValueError); ok {
+		return err
 	}
-	if err := writer.WriteByte(']'); err != nil {
-		return fmt.Errorf("failed to write ]: %w", err)
+	if pathError, ok := err.(*PathError); ok {
+		return &PathError{Elements: append([]interface{}{element}, pathError.Elements...), Err: pathError.Err}
 	}
-	return nil
+	return &PathError{Elements: []interface{}{element}, Err: err}
 }
 
 func MarshalObject(object map[string]interface{}, writer *bufio.Writer) error {
-	if err := writer.WriteByte('{'); err != nil {
-		return fmt.Errorf("failed to write {: %w", err)
+	return newEncoder(writer).marshalObject(object)
+}
+
+func (e *Encoder) marshalObject(object map[string]interface{}) error {
+	return e.marshalMap(reflect.ValueOf(object))
+}
+
+// objectMember is a single key and value to write in an object.
+type objectMember struct {
+	key   string
+	value interface{}
+}
+
+// marshalMap writes any map whose keys are strings, integers, or encoding.TextMarshalers.
+func (e *Encoder) marshalMap(object reflect.Value) error {
+	members, err := e.mapMembers(object)
+	if err != nil {
+		return err
+	}
+	return e.marshalMembers(members)
+}
+
+// mapMembers returns the members of a map in the order they are written.
+func (e *Encoder) mapMembers(object reflect.Value) ([]objectMember, error) {
+	members := make([]objectMember, 0, object.Len())
+	iter := object.MapRange()
+	for iter.Next() {
+		key, err := mapKeyString(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, objectMember{key: key, value: iter.Value().Interface()})
+	}
+	if e.canonical {
+		sortCanonical(members)
+	} else if e.SortKeys {
+		sort.Slice(members, func(i, j int) bool {
+			return members[i].key < members[j].key
+		})
+	}
+	return members, nil
+}
+
+// Pointers nested deeper than this are checked for cycles, which would otherwise recurse until the stack overflows
+const START_DETECTING_CYCLES_AFTER = 1000
+
+// marshalPointer writes null for a nil pointer and otherwise the value it points to. Each call unwraps one level, so
+// pointers to pointers and pointers to interfaces are followed until a non-pointer value or a nil is reached, and a
+// Marshaler anywhere along the way is still used.
+func (e *Encoder) marshalPointer(pointer reflect.Value) error {
+	if pointer.IsNil() {
+		return e.marshalNull()
 	}
-	i := 0
-	for key, value := range object {
-		if err := MarshalString(key, writer); err != nil {
-			return fmt.Errorf("failed to write object key %s: %w", key, err)
+	e.pointerLevel += 1
+	defer func() {
+		e.pointerLevel -= 1
+	}()
+	if e.pointerLevel > START_DETECTING_CYCLES_AFTER {
+		if e.pointersSeen == nil {
+			e.pointersSeen = make(map[uintptr]bool)
 		}
-		if err := writer.WriteByte(':'); err != nil {
-			return fmt.Errorf("failed to write ':': %w", err)
+		address := pointer.Pointer()
+		if e.pointersSeen[address] {
+			return &UnsupportedValueError{Value: pointer, Str: "it contains a cycle"}
 		}
-		if err := MarshalValue(value, writer); err != nil {
-			return fmt.Errorf("failed to write object value: %w", err)
+		e.pointersSeen[address] = true
+		defer delete(e.pointersSeen, address)
+	}
+	return e.marshalValue(pointer.Elem().Interface())
+}
+
+// marshalStruct writes a struct as an object of its exported fields, in declaration order, with the fields of embedded
+// structs promoted and the members of its remaining field, if any, written last.
+func (e *Encoder) marshalStruct(object reflect.Value) error {
+	fields := structFields(object.Type())
+	members := make([]objectMember, 0, len(fields))
+	for _, field := range fields {
+		// Fields promoted through a nil embedded pointer are left out
+		value, ok := fieldByIndex(object, field.index, false)
+		if !ok {
+			continue
 		}
-		if i < len(object)-1 {
-			if err := writer.WriteByte(','); err != nil {
-				return fmt.Errorf("failed to write ',': %w", err)
+		members = append(members, objectMember{key: field.name, value: value.Interface()})
+	}
+	if remaining, ok := remainingField(object.Type()); ok && remaining.typ.Kind() == reflect.Map {
+		// The remaining field's members follow the other fields, except for keys that a field already wrote
+		extra, err := e.mapMembers(object.FieldByIndex(remaining.index))
+		if err != nil {
+			return err
+		}
+		for _, member := range extra {
+			if !containsKey(fields, member.key) {
+				members = append(members, member)
 			}
 		}
-		i += 1
 	}
-	if err := writer.WriteByte('}'); err != nil {
-		return fmt.Errorf("failed to write }: %w", err)
+	if e.canonical {
+		sortCanonical(members)
+	}
+	return e.marshalMembers(members)
+}
+
+func containsKey(fields []structField, key string) bool {
+	for _, field := range fields {
+		if field.name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// mapKeyString converts a map key to the string used as its object key.
+func mapKeyString(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if textMarshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+		text, err := textMarshaler.MarshalText()
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal map key %v as text: %w", key, err)
+		}
+		return string(text), nil
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("cannot marshal map with key type %s", key.Type())
+}
+
+func (e *Encoder) marshalOrderedObject(object *OrderedObject) error {
+	members := make([]objectMember, len(object.keys))
+	for i, key := range object.keys {
+		members[i] = objectMember{key: key, value: object.values[key]}
+	}
+	if e.canonical {
+		sortCanonical(members)
+	}
+	return e.marshalMembers(members)
+}
+
+func (e *Encoder) marshalKeyValues(keyValues []KeyValue) error {
+	members := make([]objectMember, len(keyValues))
+	for i, keyValue := range keyValues {
+		members[i] = objectMember{key: keyValue.Key, value: keyValue.Value}
+	}
+	if e.canonical {
+		sortCanonical(members)
+	}
+	return e.marshalMembers(members)
+}
+
+// marshalMembers writes an object whose members are written in the order given.
+func (e *Encoder) marshalMembers(members []objectMember) error {
+	if err := e.openCollection('{'); err != nil {
+		return err
+	}
+	first := true
+	for _, member := range members {
+		if err := e.nextElement(first); err != nil {
+			return err
+		}
+		if err := e.marshalMember(member.key, member.value); err != nil {
+			return err
+		}
+		first = false
+	}
+	return e.closeCollection('}', first)
+}
+
+// marshalMember writes a single key and value, without the separator before them.
+func (e *Encoder) marshalMember(key string, value interface{}) error {
+	if err := e.marshalString(key); err != nil {
+		return fmt.Errorf("failed to write object key %s: %w", key, err)
+	}
+	if err := e.writer.WriteByte(':'); err != nil {
+		return fmt.Errorf("failed to write ':': %w", err)
+	}
+	if e.indented {
+		if err := e.writer.WriteByte(' '); err != nil {
+			return fmt.Errorf("failed to write ' ': %w", err)
+		}
+	}
+	if err := e.marshalValue(value); err != nil {
+		return withPathElement(key, err)
+	}
+	return nil
+}
+
+// openCollection writes the [ or { that opens an array or object and moves one level deeper.
+func (e *Encoder) openCollection(open byte) error {
+	if err := e.writer.WriteByte(open); err != nil {
+		return fmt.Errorf("failed to write %c: %w", open, err)
+	}
+	e.depth += 1
+	return nil
+}
+
+// nextElement writes the comma before every element or member but the first, then starts its line.
+func (e *Encoder) nextElement(first bool) error {
+	if !first {
+		if err := e.writer.WriteByte(','); err != nil {
+			return fmt.Errorf("failed to write ,: %w", err)
+		}
+	}
+	return e.writeIndent()
+}
+
+// closeCollection moves back out one level and writes the ] or } that closes an array or object. Empty collections
+// stay on one line.
+func (e *Encoder) closeCollection(close byte, empty bool) error {
+	e.depth -= 1
+	if !empty {
+		if err := e.writeIndent(); err != nil {
+			return err
+		}
+	}
+	if err := e.writer.WriteByte(close); err != nil {
+		return fmt.Errorf("failed to write %c: %w", close, err)
+	}
+	return nil
+}
+
+// writeIndent starts a new line at the current depth when indentation is enabled.
+func (e *Encoder) writeIndent() error {
+	if !e.indented {
+		return nil
+	}
+	if err := e.writer.WriteByte('\n'); err != nil {
+		return fmt.Errorf("failed to write newline: %w", err)
+	}
+	if _, err := e.writer.WriteString(e.prefix); err != nil {
+		return fmt.Errorf("failed to write prefix: %w", err)
+	}
+	for i := 0; i < e.depth; i++ {
+		if _, err := e.writer.WriteString(e.indent); err != nil {
+			return fmt.Errorf("failed to write indent: %w", err)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,397 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testColor string
+
+type testCelsius float64
+
+type testCount int8
+
+type testFlag bool
+
+type testPoint struct {
+	X, Y int
+}
+
+func (p testPoint) MarshalText() ([]byte, error) {
+	return []byte(strings.Repeat("x", p.X) + strings.Repeat("y", p.Y)), nil
+}
+
+type testBase struct {
+	ID   int
+	Name string
+}
+
+type testMiddle struct {
+	testBase
+	Name string
+}
+
+type testTop struct {
+	testMiddle
+	Extra int
+}
+
+type testList struct {
+	Value int
+	Next  *testList
+}
+
+// testCodedError is an error that marshals itself as an object.
+type testCodedError struct {
+	code int
+}
+
+func (e *testCodedError) Error() string {
+	return "coded error"
+}
+
+func (e *testCodedError) MarshalJSONValue(writer *bufio.Writer) error {
+	return MarshalValue(map[string]interface{}{"code": e.code}, writer)
+}
+
+// marshalRoundTrip marshals value and parses the output back.
+func marshalRoundTrip(t *testing.T, value interface{}) interface{} {
+	t.Helper()
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal(%#v) failed: %v", value, err)
+	}
+	parsed, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+	}
+	return parsed
+}
+
+func TestMarshalMapKeys(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  map[string]interface{}
+	}{
+		{map[int64]bool{10: true, -2: false}, map[string]interface{}{"10": true, "-2": false}},
+		{map[uint8]string{1: "a"}, map[string]interface{}{"1": "a"}},
+		{map[testColor]int{"red": 1, "blue": 2}, map[string]interface{}{"red": int64(1), "blue": int64(2)}},
+		{map[testPoint]int{{1, 2}: 3}, map[string]interface{}{"xyy": int64(3)}},
+	}
+	for _, test := range tests {
+		if got := marshalRoundTrip(t, test.value); !Equal(got, test.want) {
+			t.Errorf("Marshal(%#v) parsed back as %#v; want %#v", test.value, got, test.want)
+		}
+	}
+	if _, err := Marshal(map[struct{ X int }]int{{1}: 1}); err == nil {
+		t.Error("Marshal accepted a map with struct keys")
+	}
+}
+
+func TestMarshalBytesRoundTrip(t *testing.T) {
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	encoded, err := Marshal(data[:4])
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(encoded) != `"AAECAw=="` {
+		t.Errorf("Marshal = %s; want \"AAECAw==\"", encoded)
+	}
+	for _, value := range [][]byte{data, {}, {0xff}} {
+		encoded, err := Marshal(value)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var decoded []byte
+		if err := UnmarshalInto(bufio.NewReader(bytes.NewReader(encoded)), &decoded); err != nil {
+			t.Fatalf("UnmarshalInto(%s) failed: %v", encoded, err)
+		}
+		if !bytes.Equal(decoded, value) {
+			t.Errorf("round trip of %v through %s gave %v", value, encoded, decoded)
+		}
+	}
+}
+
+func benchmarkMarshalString(b *testing.B, value string) {
+	writer := bufio.NewWriter(io.Discard)
+	b.SetBytes(int64(len(value)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := MarshalString(value, writer); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMarshalStringClean writes a 10KB string with nothing to escape, which is written in one run.
+func BenchmarkMarshalStringClean(b *testing.B) {
+	benchmarkMarshalString(b, strings.Repeat("The quick brown fox jumps over the lazy dog. ", 228))
+}
+
+// BenchmarkMarshalStringEscaped writes a 10KB string with a quote every 10 bytes, for comparison.
+func BenchmarkMarshalStringEscaped(b *testing.B) {
+	benchmarkMarshalString(b, strings.Repeat(`"escaped" `, 1024))
+}
+
+func TestMarshalFloatRoundTrip(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{1e21, "1e+21"},
+		{1e-7, "1e-7"},
+		{1e20, "100000000000000000000.0"},
+		{1e-6, "0.000001"},
+		{0.1, "0.1"},
+		{123456789.125, "123456789.125"},
+		{5e-324, "5e-324"},
+		{1.7976931348623157e308, "1.7976931348623157e+308"},
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Fatalf("Marshal(%v) failed: %v", test.value, err)
+		}
+		if string(data) != test.want {
+			t.Errorf("Marshal(%v) = %s; want %s", test.value, data, test.want)
+		}
+		if parsed, err := Unmarshal(data); err != nil || parsed != test.value {
+			t.Errorf("Unmarshal(%s) = %v, %v; want %v", data, parsed, err, test.value)
+		}
+	}
+}
+
+func TestMarshalNumberTypeStable(t *testing.T) {
+	parsed, err := Unmarshal([]byte(`[2.0, 2, -0.0, 1e2, 3.5]`))
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	roundTripped := marshalRoundTrip(t, parsed)
+	want := []interface{}{float64(2), int64(2), float64(0), float64(100), 3.5}
+	values := roundTripped.([]interface{})
+	if len(values) != len(want) {
+		t.Fatalf("round trip gave %#v; want %#v", values, want)
+	}
+	for i, value := range values {
+		if reflect.TypeOf(value) != reflect.TypeOf(want[i]) || value != want[i] {
+			t.Errorf("element %d round tripped to %#v; want %#v", i, value, want[i])
+		}
+	}
+}
+
+func TestMarshalNamedScalars(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{testColor("red"), `"red"`},
+		{testCount(-3), `-3`},
+		{testCelsius(21.5), `21.5`},
+		{testFlag(true), `true`},
+		{[]testColor{"a", "b"}, `["a","b"]`},
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Errorf("Marshal(%#v) failed: %v", test.value, err)
+		} else if string(data) != test.want {
+			t.Errorf("Marshal(%#v) = %s; want %s", test.value, data, test.want)
+		}
+	}
+}
+
+func TestMarshalKeyValues(t *testing.T) {
+	data, err := Marshal([]KeyValue{{"z", 1}, {"a", 2}, {"m", []KeyValue{{"y", true}, {"b", nil}}}})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != `{"z":1,"a":2,"m":{"y":true,"b":null}}` {
+		t.Errorf("Marshal = %s; want the members in slice order", data)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{errors.New("boom"), `"boom"`},
+		{map[string]interface{}{"error": errors.New("a \"quoted\" failure")}, `{"error":"a \"quoted\" failure"}`},
+		{&testCodedError{7}, `{"code":7}`},
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Errorf("Marshal(%#v) failed: %v", test.value, err)
+		} else if string(data) != test.want {
+			t.Errorf("Marshal(%#v) = %s; want %s", test.value, data, test.want)
+		}
+	}
+}
+
+func TestMarshalOrderedObject(t *testing.T) {
+	var zero OrderedObject
+	zero.Set("z", 1)
+	zero.Set("a", 2)
+	zero.Set("z", 3)
+	var nilObject *OrderedObject
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{&zero, `{"z":3,"a":2}`},
+		{&OrderedObject{}, `{}`},
+		{nilObject, `null`},
+		{struct{ Object *OrderedObject }{}, `{"Object":null}`},
+		{map[string]interface{}{"object": nilObject}, `{"object":null}`},
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Errorf("Marshal(%#v) failed: %v", test.value, err)
+		} else if string(data) != test.want {
+			t.Errorf("Marshal(%#v) = %s; want %s", test.value, data, test.want)
+		}
+	}
+}
+
+// marshalIndentString returns the output of MarshalIndent as a string.
+func marshalIndentString(t *testing.T, value interface{}, prefix, indent string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	if err := MarshalIndent(value, writer, prefix, indent); err != nil {
+		t.Fatalf("MarshalIndent(%#v) failed: %v", value, err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("failed to flush writer: %v", err)
+	}
+	return buf.String()
+}
+
+func TestMarshalEmptyCollections(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		compact  string
+		indented string
+	}{
+		{[]interface{}{}, `[]`, `[]`},
+		{map[string]interface{}{}, `{}`, `{}`},
+		{[]interface{}{[]interface{}{}}, `[[]]`, "[\n  []\n]"},
+		{map[string]interface{}{"a": map[string]interface{}{}}, `{"a":{}}`, "{\n  \"a\": {}\n}"},
+		{
+			map[string]interface{}{"a": []interface{}{map[string]interface{}{}, []int{}}},
+			`{"a":[{},[]]}`,
+			"{\n  \"a\": [\n    {},\n    []\n  ]\n}",
+		},
+		{[]interface{}{[]interface{}{[]interface{}{}}}, `[[[]]]`, "[\n  [\n    []\n  ]\n]"},
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Errorf("Marshal(%#v) failed: %v", test.value, err)
+		} else if string(data) != test.compact {
+			t.Errorf("Marshal(%#v) = %s; want %s", test.value, data, test.compact)
+		}
+		if indented := marshalIndentString(t, test.value, "", "  "); indented != test.indented {
+			t.Errorf("MarshalIndent(%#v) = %q; want %q", test.value, indented, test.indented)
+		}
+	}
+	if indented := marshalIndentString(t, []interface{}{map[string]interface{}{}}, "> ", "\t"); indented != "[\n> \t{}\n> ]" {
+		t.Errorf("MarshalIndent with a prefix = %q", indented)
+	}
+}
+
+func TestMarshalLineSeparators(t *testing.T) {
+	value := map[string]interface{}{"a\u2028b": "c\u2029d"}
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := `{"a\u2028b":"c\u2029d"}`; string(data) != want {
+		t.Errorf("Marshal = %s; want %s", data, want)
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(value); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := "{\"a\u2028b\":\"c\u2029d\"}"; buf.String() != want {
+		t.Errorf("Encode without HTML escaping = %q; want %q", buf.String(), want)
+	}
+}
+
+func TestMarshalEmbeddedStructs(t *testing.T) {
+	value := testTop{testMiddle{testBase{1, "base"}, "mid"}, 3}
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	// testMiddle.Name shadows the deeper testBase.Name
+	if want := `{"ID":1,"Name":"mid","Extra":3}`; string(data) != want {
+		t.Errorf("Marshal = %s; want %s", data, want)
+	}
+	var decoded testTop
+	if err := UnmarshalInto(bufio.NewReader(bytes.NewReader(data)), &decoded); err != nil {
+		t.Fatalf("UnmarshalInto(%s) failed: %v", data, err)
+	}
+	if want := (testTop{testMiddle{testBase{1, ""}, "mid"}, 3}); decoded != want {
+		t.Errorf("UnmarshalInto(%s) = %+v; want %+v", data, decoded, want)
+	}
+}
+
+func TestMarshalSkippedFieldsCommas(t *testing.T) {
+	type partial struct {
+		hidden int
+		Skip   int `json:"-"`
+		B      int
+		Also   int `json:"-"`
+		C      int
+	}
+	type allSkipped struct {
+		hidden int
+		Skip   int `json:"-"`
+	}
+	tests := []struct {
+		value    interface{}
+		compact  string
+		indented string
+	}{
+		{partial{1, 2, 3, 4, 5}, `{"B":3,"C":5}`, "{\n  \"B\": 3,\n  \"C\": 5\n}"},
+		{allSkipped{1, 2}, `{}`, `{}`},
+		{[]interface{}{allSkipped{}}, `[{}]`, "[\n  {}\n]"},
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Errorf("Marshal(%#v) failed: %v", test.value, err)
+		} else if string(data) != test.compact {
+			t.Errorf("Marshal(%#v) = %s; want %s", test.value, data, test.compact)
+		}
+		if indented := marshalIndentString(t, test.value, "", "  "); indented != test.indented {
+			t.Errorf("MarshalIndent(%#v) = %q; want %q", test.value, indented, test.indented)
+		}
+	}
+}
+
+func TestMarshalPointerCycle(t *testing.T) {
+	list := &testList{Value: 1}
+	list.Next = &testList{Value: 2, Next: list}
+	_, err := Marshal(map[string]interface{}{"list": []interface{}{list}})
+	var valueError *UnsupportedValueError
+	if !errors.As(err, &valueError) {
+		t.Fatalf("Marshal of a cycle = %v; want an UnsupportedValueError", err)
+	}
+	if want := "cannot marshal value of type *json.testList, it contains a cycle"; err.Error() != want {
+		t.Errorf("Marshal of a cycle = %q; want %q", err, want)
+	}
+}
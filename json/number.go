@@ -0,0 +1,84 @@
+package json
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Number is a JSON number literal kept as its original text. The decoder produces it when UseNumber is set, and
+// MarshalValue writes it back unchanged.
+type Number string
+
+func (n Number) String() string {
+	return string(n)
+}
+
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// NumberMode chooses the Go type that numbers decode to when the target is an interface{}.
+type NumberMode int
+
+const (
+	// NUMBER_MODE_AUTO decodes integers as int64 and numbers with a fraction or exponent as float64.
+	NUMBER_MODE_AUTO NumberMode = iota
+	// NUMBER_MODE_FLOAT64 decodes every number as float64, as JavaScript does.
+	NUMBER_MODE_FLOAT64
+	// NUMBER_MODE_NUMBER decodes every number as Number, preserving its exact text. It is the same as UseNumber.
+	NUMBER_MODE_NUMBER
+)
+
+// numberMode returns the NumberMode in effect, which UseNumber overrides.
+func (d *Decoder) numberMode() NumberMode {
+	if d.UseNumber {
+		return NUMBER_MODE_NUMBER
+	}
+	return d.NumberMode
+}
+
+// integerText rewrites a number literal as the integer it equals, such as 1.5e3 as 1500, reporting false if it has a
+// fractional part or too many digits for any Go integer type.
+func integerText(number string) (string, bool) {
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+	mantissa, exponent := number, 0
+	if i := strings.IndexAny(number, "eE"); i >= 0 {
+		var err error
+		if exponent, err = strconv.Atoi(number[i+1:]); err != nil {
+			return "", false
+		}
+		mantissa = number[:i]
+	}
+	integer, fraction, _ := strings.Cut(mantissa, ".")
+	digits := strings.TrimLeft(integer+fraction, "0")
+	exponent -= len(fraction)
+	if digits == "" {
+		return "0", true
+	}
+	if exponent < 0 {
+		// The digits moved past the decimal point must all be zero
+		if -exponent > len(digits) || strings.TrimRight(digits[len(digits)+exponent:], "0") != "" {
+			return "", false
+		}
+		return sign + digits[:len(digits)+exponent], true
+	}
+	if len(digits)+exponent > 20 {
+		return "", false
+	}
+	return sign + digits + strings.Repeat("0", exponent), true
+}
+
+// isValidNumber reports whether s is exactly one JSON number literal.
+func isValidNumber(s string) bool {
+	d := newBytesDecoder([]byte(s))
+	d.UseNumber = true
+	_, err := d.unmarshalNumber()
+	return err == nil && d.reader.offset == int64(len(s))
+}
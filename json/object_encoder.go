@@ -0,0 +1,66 @@
+package json
+
+import "fmt"
+
+// ObjectEncoder writes a JSON object one member at a time, so members can be computed as they are written instead of
+// collected in a map first. Call Open, then Field for each member, then Close. Keys are written in the order given.
+type ObjectEncoder struct {
+	encoder *Encoder
+	opened  bool
+	closed  bool
+	count   int
+}
+
+// NewObjectEncoder returns an ObjectEncoder writing through e, using its options and indentation.
+func NewObjectEncoder(e *Encoder) *ObjectEncoder {
+	return &ObjectEncoder{encoder: e}
+}
+
+// Open writes the opening { of the object.
+func (o *ObjectEncoder) Open() error {
+	if o.opened {
+		return fmt.Errorf("cannot Open object, already opened")
+	}
+	o.opened = true
+	return o.encoder.openCollection('{')
+}
+
+// Field writes key and value as the next member of the object. Keys are not checked for duplicates. Since output is
+// streamed, a value that fails to marshal leaves the object incomplete.
+func (o *ObjectEncoder) Field(key string, value interface{}) error {
+	if err := o.checkOpen("write object field " + key); err != nil {
+		return err
+	}
+	if err := o.encoder.nextElement(o.count == 0); err != nil {
+		return err
+	}
+	if err := o.encoder.marshalMember(key, value); err != nil {
+		return err
+	}
+	o.count += 1
+	return nil
+}
+
+// Close writes the closing } of the object and flushes it to the output.
+func (o *ObjectEncoder) Close() error {
+	if err := o.checkOpen("Close object"); err != nil {
+		return err
+	}
+	o.closed = true
+	if err := o.encoder.closeCollection('}', o.count == 0); err != nil {
+		return err
+	}
+	if err := o.encoder.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
+
+func (o *ObjectEncoder) checkOpen(action string) error {
+	if !o.opened {
+		return fmt.Errorf("cannot %s, object was never opened", action)
+	} else if o.closed {
+		return fmt.Errorf("cannot %s, object is already closed", action)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package json
+
+// OrderedObject is a JSON object that remembers the order in which its keys were first set.
+// The decoder produces it when UseOrderedObjects is set, and MarshalValue writes its members in that order.
+type OrderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func NewOrderedObject() *OrderedObject {
+	return &OrderedObject{values: make(map[string]interface{})}
+}
+
+// Set stores value under key. Setting an existing key replaces its value but keeps its position. The zero
+// OrderedObject is empty and ready to use.
+func (o *OrderedObject) Set(key string, value interface{}) {
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *OrderedObject) Get(key string) (interface{}, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// Keys returns the keys in insertion order.
+func (o *OrderedObject) Keys() []string {
+	return o.keys
+}
+
+func (o *OrderedObject) Len() int {
+	return len(o.keys)
+}
+
+// KeyValue is one member of an object. A []KeyValue marshals as an object with its members in slice order, for output
+// whose key order is fixed by the caller. Keys are not checked for duplicates.
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
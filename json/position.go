@@ -0,0 +1,216 @@
+package json
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// positionReader wraps a bufio.Reader, or walks a byte slice directly, and tracks the byte offset, line, and column
+// of the runes consumed from it. Lines start at 1 and the column is the number of runes consumed on the current line,
+// so after reading a rune the position points at that rune.
+type positionReader struct {
+	reader *bufio.Reader
+	offset int64
+	line   int
+	column int
+	// Position before the last ReadRune, restored by UnreadRune
+	prevOffset int64
+	prevLine   int
+	prevColumn int
+	// Bytes consumed since startCapture, or nil when not capturing
+	capture  []byte
+	lastSize int
+	// Offset that reads may not go past, or 0 for no limit, and the byte count it was set from
+	limit    int64
+	maxBytes int64
+	// Input read in place when reader is nil, the index of the next unread byte, and whether the last read was a
+	// ReadRune that UnreadRune may undo
+	data      []byte
+	pos       int
+	canUnread bool
+}
+
+func newPositionReader(reader *bufio.Reader) *positionReader {
+	return &positionReader{reader: reader, line: 1, prevLine: 1}
+}
+
+func newBytesPositionReader(data []byte) *positionReader {
+	return &positionReader{data: data, line: 1, prevLine: 1}
+}
+
+func (p *positionReader) ReadRune() (rune, int, error) {
+	if err := p.checkLimit(); err != nil {
+		return 0, 0, err
+	}
+	var raw []byte
+	var r rune
+	var size int
+	if p.reader == nil {
+		if p.pos >= len(p.data) {
+			p.canUnread = false
+			return 0, 0, io.EOF
+		}
+		raw = p.data[p.pos:]
+		if raw[0] < utf8.RuneSelf {
+			r, size = rune(raw[0]), 1
+		} else {
+			r, size = utf8.DecodeRune(raw)
+		}
+		p.pos += size
+		p.canUnread = true
+	} else {
+		if p.capture != nil {
+			// ReadRune replaces invalid bytes with U+FFFD, so keep the original bytes for the capture
+			raw, _ = p.reader.Peek(utf8.UTFMax)
+		}
+		var err error
+		r, size, err = p.reader.ReadRune()
+		if err != nil {
+			return r, size, err
+		}
+	}
+	if p.capture != nil {
+		p.capture = append(p.capture, raw[:size]...)
+	}
+	p.prevOffset, p.prevLine, p.prevColumn = p.offset, p.line, p.column
+	p.lastSize = size
+	p.advance(r, size)
+	return r, size, nil
+}
+
+func (p *positionReader) UnreadRune() error {
+	if p.reader == nil {
+		if !p.canUnread {
+			return bufio.ErrInvalidUnreadRune
+		}
+		p.pos -= p.lastSize
+		p.canUnread = false
+	} else if err := p.reader.UnreadRune(); err != nil {
+		return err
+	}
+	if p.capture != nil {
+		p.capture = p.capture[:len(p.capture)-p.lastSize]
+	}
+	p.offset, p.line, p.column = p.prevOffset, p.prevLine, p.prevColumn
+	return nil
+}
+
+func (p *positionReader) Read(buf []byte) (int, error) {
+	if err := p.checkLimit(); err != nil {
+		return 0, err
+	}
+	var n int
+	var err error
+	if p.reader == nil {
+		n = copy(buf, p.data[p.pos:])
+		p.pos += n
+		p.canUnread = false
+		if n == 0 && len(buf) > 0 {
+			err = io.EOF
+		}
+	} else {
+		n, err = p.reader.Read(buf)
+	}
+	if p.capture != nil {
+		p.capture = append(p.capture, buf[:n]...)
+	}
+	for i := 0; i < n; i++ {
+		if buf[i] == '\n' || utf8.RuneStart(buf[i]) {
+			p.advance(rune(buf[i]), 1)
+		} else {
+			p.offset += 1
+		}
+	}
+	return n, err
+}
+
+// buffered returns the bytes that can be read without touching the underlying reader, without consuming them.
+func (p *positionReader) buffered() []byte {
+	var n int
+	if p.reader == nil {
+		n = len(p.data) - p.pos
+	} else {
+		n = p.reader.Buffered()
+	}
+	if p.limit > 0 && int64(n) > p.limit-p.offset {
+		n = int(p.limit - p.offset)
+	}
+	if n <= 0 {
+		return nil
+	}
+	if p.reader == nil {
+		return p.data[p.pos : p.pos+n]
+	}
+	buf, _ := p.reader.Peek(n)
+	return buf
+}
+
+// discard consumes n bytes of buffered text that hold the given number of runes and no newlines.
+func (p *positionReader) discard(n int, runes int) error {
+	if p.capture != nil {
+		p.capture = append(p.capture, p.buffered()[:n]...)
+	}
+	var discarded int
+	var err error
+	if p.reader == nil {
+		discarded = n
+		p.pos += n
+		p.canUnread = false
+	} else {
+		discarded, err = p.reader.Discard(n)
+	}
+	p.offset += int64(discarded)
+	if discarded == n {
+		p.column += runes
+	}
+	return err
+}
+
+// setLimit allows at most maxBytes more bytes to be consumed, or any number if maxBytes is 0 or less.
+func (p *positionReader) setLimit(maxBytes int64) {
+	p.maxBytes = maxBytes
+	p.limit = 0
+	if maxBytes > 0 {
+		p.limit = p.offset + maxBytes
+	}
+}
+
+// checkLimit fails once the limit has been passed. A single read may cross the limit so that the rune after a value
+// can still be peeked, but nothing is read after that until it is unread.
+func (p *positionReader) checkLimit() error {
+	if p.limit > 0 && p.offset > p.limit {
+		return fmt.Errorf("input exceeds %d byte limit", p.maxBytes)
+	}
+	return nil
+}
+
+func (p *positionReader) advance(r rune, size int) {
+	p.offset += int64(size)
+	if r == '\n' {
+		p.line += 1
+		p.column = 0
+	} else {
+		p.column += 1
+	}
+}
+
+// startCapture begins recording every byte consumed until stopCapture.
+func (p *positionReader) startCapture() {
+	p.capture = []byte{}
+}
+
+func (p *positionReader) stopCapture() []byte {
+	captured := p.capture
+	p.capture = nil
+	return captured
+}
+
+// wrapError returns a SyntaxError wrapping err at the current position, or nil if err is nil.
+func (p *positionReader) wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &SyntaxError{Offset: p.offset, Line: p.line, Column: p.column, Msg: err.Error(), Err: err}
+}
@@ -0,0 +1,29 @@
+package json
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// RawMessage is a raw encoded JSON value. Decoding into a RawMessage keeps the value's bytes exactly as they appear in
+// the input so they can be parsed later, and marshaling writes them back unchanged. A struct field of type RawMessage
+// therefore passes its part of a document through DecodeInto and Marshal untouched, including a null.
+type RawMessage []byte
+
+func (m RawMessage) MarshalJSONValue(writer *bufio.Writer) error {
+	if m == nil {
+		return MarshalNull(writer)
+	}
+	if !Valid(m) {
+		return fmt.Errorf("invalid JSON in RawMessage: %s", m)
+	}
+	if _, err := writer.Write(m); err != nil {
+		return fmt.Errorf("failed to write RawMessage: %w", err)
+	}
+	return nil
+}
+
+func (m *RawMessage) UnmarshalJSONValue(data []byte) error {
+	*m = append((*m)[0:0], data...)
+	return nil
+}
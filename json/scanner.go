@@ -0,0 +1,117 @@
+package json
+
+import (
+	"fmt"
+	"io"
+)
+
+// TokenKind classifies a ScannedToken.
+type TokenKind int
+
+const (
+	TOKEN_BEGIN_OBJECT TokenKind = iota
+	TOKEN_END_OBJECT
+	TOKEN_BEGIN_ARRAY
+	TOKEN_END_ARRAY
+	TOKEN_COMMA
+	TOKEN_COLON
+	TOKEN_STRING
+	TOKEN_NUMBER
+	TOKEN_BOOL
+	TOKEN_NULL
+)
+
+var tokenKindNames = [...]string{"{", "}", "[", "]", ",", ":", "string", "number", "bool", "null"}
+
+var punctuationKinds = map[rune]TokenKind{
+	'{': TOKEN_BEGIN_OBJECT,
+	'}': TOKEN_END_OBJECT,
+	'[': TOKEN_BEGIN_ARRAY,
+	']': TOKEN_END_ARRAY,
+	',': TOKEN_COMMA,
+	':': TOKEN_COLON,
+}
+
+func (k TokenKind) String() string {
+	if k < 0 || int(k) >= len(tokenKindNames) {
+		return fmt.Sprintf("TokenKind(%d)", int(k))
+	}
+	return tokenKindNames[k]
+}
+
+// ScannedToken is a single lexical token. Offset is the byte offset of its first byte in the input, and Raw holds its
+// bytes exactly as written. Value is the decoded token: a Delim, string, Number, bool, or nil.
+type ScannedToken struct {
+	Kind   TokenKind
+	Offset int64
+	Raw    []byte
+	Value  Token
+}
+
+// Scanner splits JSON text into lexical tokens, including commas and colons, without checking that they form a valid
+// document. It is meant for tools such as linters and syntax highlighters that work below the level of values.
+type Scanner struct {
+	decoder *Decoder
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	decoder := NewDecoder(r)
+	decoder.UseNumber = true
+	return &Scanner{decoder: decoder}
+}
+
+// Next returns the next token, or io.EOF once only whitespace remains.
+func (s *Scanner) Next() (ScannedToken, error) {
+	token, err := s.next()
+	if err != nil && err != io.EOF {
+		return token, s.decoder.wrapError(err)
+	}
+	return token, err
+}
+
+func (s *Scanner) next() (ScannedToken, error) {
+	d := s.decoder
+	eof, err := d.atEOF()
+	if err != nil {
+		return ScannedToken{}, err
+	}
+	if eof {
+		return ScannedToken{}, io.EOF
+	}
+	r, err := d.peekRune()
+	if err != nil {
+		return ScannedToken{}, err
+	}
+	token := ScannedToken{Offset: d.reader.offset}
+	d.reader.startCapture()
+	switch {
+	case r == '{' || r == '}' || r == '[' || r == ']' || r == ',' || r == ':':
+		_, _, err = d.reader.ReadRune()
+		token.Kind = punctuationKinds[r]
+		if r != ',' && r != ':' {
+			token.Value = Delim(r)
+		}
+	case r == '"':
+		token.Kind = TOKEN_STRING
+		token.Value, err = d.unmarshalString()
+	case isDigit(r) || r == '-':
+		token.Kind = TOKEN_NUMBER
+		token.Value, err = d.unmarshalNumber()
+	case r == 't':
+		token.Kind = TOKEN_BOOL
+		token.Value, err = d.unmarshalTrue()
+	case r == 'f':
+		token.Kind = TOKEN_BOOL
+		token.Value, err = d.unmarshalFalse()
+	case r == 'n':
+		token.Kind = TOKEN_NULL
+		token.Value, err = d.unmarshalNull()
+	default:
+		err = fmt.Errorf("unexpected character: %c", r)
+	}
+	token.Raw = d.reader.stopCapture()
+	if err != nil {
+		return ScannedToken{}, err
+	}
+	return token, nil
+}
@@ -0,0 +1,184 @@
+package json
+
+import (
+	"fmt"
+	"io"
+)
+
+// Token is a single lexical JSON token: a Delim, string, number, bool, or nil for null.
+type Token interface{}
+
+// Delim is one of the JSON delimiters [ ] { }
+type Delim rune
+
+func (d Delim) String() string {
+	return string(d)
+}
+
+// Token returns the next token in the input, skipping commas and colons. It returns io.EOF once the input ends
+// outside of any array or object. Decode may be called between tokens to read a whole value at once.
+func (d *Decoder) Token() (Token, error) {
+	token, err := d.token()
+	if err != nil && err != io.EOF {
+		return nil, d.wrapError(err)
+	}
+	return token, err
+}
+
+func (d *Decoder) token() (Token, error) {
+	// States
+	// 0 top-level value
+	// 1 [
+	// 2 [ ... value,
+	// 3 [ ... value
+	// 4 {
+	// 5 { ... key:value,
+	// 6 { ... key
+	// 7 { ... key:
+	// 8 { ... key:value
+	for {
+		if err := d.unmarshalWhitespace(); err != nil {
+			return nil, err
+		}
+		r, _, err := d.reader.ReadRune()
+		if err == io.EOF && len(d.tokenStack) == 0 {
+			return nil, io.EOF
+		} else if err == io.EOF {
+			return nil, fmt.Errorf("unexpected end of input inside %s: %w", d.openDelim(), io.ErrUnexpectedEOF)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
+		}
+		switch {
+		case r == ',' && d.tokenState == 3:
+			d.tokenState = 2
+		case r == ',' && d.tokenState == 8:
+			d.tokenState = 5
+		case r == ':' && d.tokenState == 6:
+			d.tokenState = 7
+		case r == ']' && (d.tokenState == 1 || d.tokenState == 3 || (d.tokenState == 2 && d.AllowTrailingComma)):
+			d.closeToken()
+			return Delim(r), nil
+		case r == '}' && (d.tokenState == 4 || d.tokenState == 8 || (d.tokenState == 5 && d.AllowTrailingComma)):
+			d.closeToken()
+			return Delim(r), nil
+		case r == '"' && (d.tokenState == 4 || d.tokenState == 5):
+			if err := d.reader.UnreadRune(); err != nil {
+				return nil, fmt.Errorf("failed to unread rune: %w", err)
+			}
+			key, err := d.unmarshalKey()
+			if err != nil {
+				return nil, err
+			}
+			d.tokenState = 6
+			return key, nil
+		case d.tokenValueAllowed() && (r == '[' || r == '{'):
+			if err := d.enterNesting(); err != nil {
+				return nil, err
+			}
+			d.tokenStack = append(d.tokenStack, d.tokenState)
+			if r == '[' {
+				d.tokenState = 1
+			} else {
+				d.tokenState = 4
+			}
+			return Delim(r), nil
+		case d.tokenValueAllowed():
+			if err := d.reader.UnreadRune(); err != nil {
+				return nil, fmt.Errorf("failed to unread rune: %w", err)
+			}
+			value, err := d.unmarshalValue()
+			if err != nil {
+				return nil, err
+			}
+			d.tokenValueEnd()
+			return value, nil
+		default:
+			return nil, fmt.Errorf("unexpected %c in token stream", r)
+		}
+	}
+}
+
+// More reports whether another element or member follows in the array or object being read with Token, or at the top
+// level whether another value follows. It is false at a closing delimiter, at the end of the input, and on errors,
+// which the next call to Token reports.
+func (d *Decoder) More() bool {
+	r, err := d.peekSignificant()
+	if err != nil {
+		return false
+	}
+	if r == ',' && (d.tokenState == 3 || d.tokenState == 8) {
+		// Consume the comma as Token would, so a trailing comma before the closing delimiter is seen past
+		if _, _, err := d.reader.ReadRune(); err != nil {
+			return false
+		}
+		if d.tokenState == 3 {
+			d.tokenState = 2
+		} else {
+			d.tokenState = 5
+		}
+		if r, err = d.peekSignificant(); err != nil {
+			return false
+		}
+	}
+	return r != ']' && r != '}'
+}
+
+// peekSignificant skips whitespace and returns the next rune without consuming it.
+func (d *Decoder) peekSignificant() (rune, error) {
+	if err := d.unmarshalWhitespace(); err != nil {
+		return 0, err
+	}
+	return d.peekRune()
+}
+
+func (d *Decoder) tokenValueAllowed() bool {
+	return d.tokenState == 0 || d.tokenState == 1 || d.tokenState == 2 || d.tokenState == 7
+}
+
+// tokenValueEnd moves the token state past a completed value.
+func (d *Decoder) tokenValueEnd() {
+	if d.tokenState == 1 || d.tokenState == 2 {
+		d.tokenState = 3
+	} else if d.tokenState == 7 {
+		d.tokenState = 8
+	}
+}
+
+func (d *Decoder) closeToken() {
+	d.exitNesting()
+	d.tokenState = d.tokenStack[len(d.tokenStack)-1]
+	d.tokenStack = d.tokenStack[:len(d.tokenStack)-1]
+	d.tokenValueEnd()
+}
+
+func (d *Decoder) openDelim() string {
+	if d.tokenState <= 3 {
+		return "array"
+	}
+	return "object"
+}
+
+// prepareTokenForDecode consumes the comma or colon that Token would have skipped before the next value.
+func (d *Decoder) prepareTokenForDecode() error {
+	if d.tokenState != 3 && d.tokenState != 6 {
+		if !d.tokenValueAllowed() {
+			return fmt.Errorf("cannot Decode an object key, expected string token")
+		}
+		return nil
+	}
+	if err := d.unmarshalWhitespace(); err != nil {
+		return err
+	}
+	r, _, err := d.reader.ReadRune()
+	if err != nil {
+		return fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
+	}
+	if d.tokenState == 3 && r == ',' {
+		d.tokenState = 2
+	} else if d.tokenState == 6 && r == ':' {
+		d.tokenState = 7
+	} else {
+		return fmt.Errorf("expected , or : before value, found: %c", r)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package json
+
+import (
+	"math"
+	"reflect"
+)
+
+// DeepCopy returns a copy of a decoded value that shares no maps, slices, or OrderedObjects with it, so either can be
+// modified without affecting the other. Strings, numbers, bools, and nil are immutable and returned as they are, as
+// is any other type, which the decoder never produces.
+func DeepCopy(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if value == nil {
+			return value
+		}
+		copied := make(map[string]interface{}, len(value))
+		for key, element := range value {
+			copied[key] = DeepCopy(element)
+		}
+		return copied
+	case []interface{}:
+		if value == nil {
+			return value
+		}
+		copied := make([]interface{}, len(value))
+		for i, element := range value {
+			copied[i] = DeepCopy(element)
+		}
+		return copied
+	case *OrderedObject:
+		if value == nil {
+			return value
+		}
+		copied := &OrderedObject{keys: append([]string(nil), value.keys...), values: make(map[string]interface{}, len(value.values))}
+		for key, element := range value.values {
+			copied.values[key] = DeepCopy(element)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// Equal reports whether two decoded values represent the same JSON. Objects are equal when they have the same keys
+// with equal values in any order, whether they are maps or OrderedObjects, and arrays when their elements are equal in
+// order. Numbers are compared by value, so int64 1, float64 1, and Number("1.0") are all equal.
+func Equal(a, b interface{}) bool {
+	if aNumber, ok := numberValue(a); ok {
+		bNumber, ok := numberValue(b)
+		return ok && numbersEqual(aNumber, bNumber)
+	}
+	if aMembers, ok := objectMembers(a); ok {
+		bMembers, ok := objectMembers(b)
+		if !ok || len(aMembers) != len(bMembers) {
+			return false
+		}
+		for key, aValue := range aMembers {
+			bValue, ok := bMembers[key]
+			if !ok || !Equal(aValue, bValue) {
+				return false
+			}
+		}
+		return true
+	}
+	if aElements, ok := a.([]interface{}); ok {
+		bElements, ok := b.([]interface{})
+		if !ok || len(aElements) != len(bElements) {
+			return false
+		}
+		for i := range aElements {
+			if !Equal(aElements[i], bElements[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// numberValue converts a decoded number to int64 or float64. Numbers too large for a float64 are left for
+// reflect.DeepEqual to compare as text.
+func numberValue(value interface{}) (interface{}, bool) {
+	switch value := value.(type) {
+	case int64, float64:
+		return value, true
+	case Number:
+		converted, err := convertToNumber(string(value))
+		return converted, err == nil
+	}
+	return nil, false
+}
+
+// numbersEqual compares two numbers from numberValue without rounding the int64 to a float64.
+func numbersEqual(a, b interface{}) bool {
+	aInt, aIsInt := a.(int64)
+	bInt, bIsInt := b.(int64)
+	switch {
+	case aIsInt && bIsInt:
+		return aInt == bInt
+	case aIsInt:
+		return floatEqualsInt(b.(float64), aInt)
+	case bIsInt:
+		return floatEqualsInt(a.(float64), bInt)
+	}
+	return a.(float64) == b.(float64)
+}
+
+func floatEqualsInt(f float64, i int64) bool {
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && int64(f) == i
+}
+
+// objectMembers returns the members of a map or OrderedObject.
+func objectMembers(value interface{}) (map[string]interface{}, bool) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return value, true
+	case *OrderedObject:
+		if value == nil {
+			return nil, false
+		}
+		return value.values, true
+	}
+	return nil, false
+}
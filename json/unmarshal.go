@@ -5,75 +5,251 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
-	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 const TRUE_STRING = "true"
 const FALSE_STRING = "false"
 const NULL_STRING = "null"
 
-var UNICODE_INSUFFICIENT_BYTES = errors.New("failed reading all 4 hex chars for unicode")
+// The byte order mark some editors write at the start of UTF-8 files
+const BYTE_ORDER_MARK = '\uFEFF'
 
-func UnmarshalValue(reader *bufio.Reader) (value interface{}, err error) {
+// UNICODE_INSUFFICIENT_BYTES is returned when the input ends inside a \u escape. It wraps io.ErrUnexpectedEOF, like
+// any other value cut off partway through.
+var UNICODE_INSUFFICIENT_BYTES = fmt.Errorf("failed reading all 4 hex chars for unicode: %w", io.ErrUnexpectedEOF)
+
+// EMPTY_INPUT is returned when the input ends, possibly after whitespace, before any value starts. A value cut off
+// partway through is a different error.
+var EMPTY_INPUT = errors.New("no JSON value in input")
+
+// Unmarshal parses data as a single JSON value. Anything but whitespace after the value is an error.
+func Unmarshal(data []byte) (interface{}, error) {
+	return UnmarshalBytes(data)
+}
+
+// UnmarshalBytes parses data as a single JSON value like UnmarshalComplete, but walks the slice directly instead of
+// going through a bufio.Reader, which saves the reader's buffer and a copy of the input.
+func UnmarshalBytes(data []byte) (interface{}, error) {
+	d := newBytesDecoder(data)
+	value, err := d.unmarshalComplete()
+	if err != nil {
+		return nil, d.wrapError(err)
+	}
+	return value, nil
+}
+
+// UnmarshalComplete parses a single JSON value and verifies that the reader holds nothing after it but whitespace.
+// The value may be a bare scalar, so input holding just 42, "x", or true is accepted, while true false is an error
+// since a second value follows the first.
+func UnmarshalComplete(reader *bufio.Reader) (interface{}, error) {
+	d := newDecoder(reader)
+	value, err := d.unmarshalComplete()
+	if err != nil {
+		return nil, d.wrapError(err)
+	}
+	return value, nil
+}
+
+func (d *Decoder) unmarshalComplete() (interface{}, error) {
+	value, err := d.unmarshalValue()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expectEOF(); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// UnmarshalValue parses the next JSON value from reader along with the whitespace around it, leaving anything after
+// that unread. Use UnmarshalComplete to require that the value is all the reader holds.
+func UnmarshalValue(reader *bufio.Reader) (interface{}, error) {
+	d := newDecoder(reader)
+	value, err := d.unmarshalValue()
+	if err != nil {
+		return nil, d.wrapError(err)
+	}
+	return value, nil
+}
+
+// UnmarshalValueN is like UnmarshalValue but also returns the number of bytes consumed from reader. The count includes
+// the whitespace before and after the value, since UnmarshalValue consumes both.
+func UnmarshalValueN(reader *bufio.Reader) (interface{}, int, error) {
+	d := newDecoder(reader)
+	value, err := d.unmarshalValue()
+	n := int(d.reader.offset)
+	if err != nil {
+		return nil, n, d.wrapError(err)
+	}
+	return value, n, nil
+}
+
+func (d *Decoder) unmarshalValue() (value interface{}, err error) {
+	if value, err = d.unmarshalBareValue(); err != nil {
+		return value, err
+	}
+	// Unmarshal trailing whitespace
+	if err := d.unmarshalWhitespace(); err != nil {
+		return value, fmt.Errorf("failed to Unmarshal trailing whitespace: %w", err)
+	}
+	return value, nil
+}
+
+// unmarshalBareValue is unmarshalValue without the trailing whitespace, so the reader stops at the end of the value.
+func (d *Decoder) unmarshalBareValue() (value interface{}, err error) {
+	if err = d.checkContext(); err != nil {
+		return nil, err
+	}
 	// Unmarshal leading whitespace
-	if err = UnmarshalWhitespace(reader); err != nil {
+	if err = d.unmarshalWhitespace(); err != nil {
 		return nil, fmt.Errorf("failed to Unmarshal leading whitespace: %w", err)
 	}
 	// Peek at the first rune
-	r, _, err := reader.ReadRune()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read rune: %w", err)
-	}
-	if err = reader.UnreadRune(); err != nil {
-		return nil, fmt.Errorf("failed to unread rune: %w", err)
+	r, err := d.peekRune()
+	if errors.Is(err, io.EOF) && d.depth == 0 {
+		return nil, EMPTY_INPUT
+	} else if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("expected value: %w", io.ErrUnexpectedEOF)
+	} else if err != nil {
+		return nil, err
 	}
 	// Call correct parsing function depending on the first rune
 	if r == '"' {
-		value, err = UnmarshalString(reader)
-	} else if unicode.IsDigit(r) || r == '-' {
-		value, err = UnmarshalNumber(reader)
+		value, err = d.unmarshalString()
+	} else if isDigit(r) || r == '-' {
+		value, err = d.unmarshalNumber()
+	} else if r == '{' && d.UseOrderedObjects {
+		value, err = d.unmarshalOrderedObject()
 	} else if r == '{' {
-		value, err = UnmarshalObject(reader)
+		value, err = d.unmarshalObject()
 	} else if r == '[' {
-		value, err = UnmarshalArray(reader)
+		value, err = d.unmarshalArray()
 	} else if r == 't' {
-		value, err = UnmarshalTrue(reader)
+		value, err = d.unmarshalTrue()
 	} else if r == 'f' {
-		value, err = UnmarshalFalse(reader)
+		value, err = d.unmarshalFalse()
 	} else if r == 'n' {
-		value, err = UnmarshalNull(reader)
+		value, err = d.unmarshalNull()
 	} else {
 		return nil, fmt.Errorf("failed to match value given first char: %c", r)
 	}
-	// Unmarshal trailing whitespace
-	if err := UnmarshalWhitespace(reader); err != nil {
-		return nil, fmt.Errorf("failed to Unmarshal trailing whitespace: %w", err)
+	if err != nil {
+		// Arrays and objects return what they parsed before the error, for DecodePartial
+		if r != '{' && r != '[' {
+			value = nil
+		}
+		return value, err
+	}
+	return value, nil
+}
+
+func (d *Decoder) peekRune() (rune, error) {
+	r, _, err := d.reader.ReadRune()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rune: %w", err)
+	}
+	if err = d.reader.UnreadRune(); err != nil {
+		return 0, fmt.Errorf("failed to unread rune: %w", err)
 	}
-	return value, err
+	return r, nil
 }
 
-func isJsonWhitespace(r rune) bool {
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF, for reads that need more input to finish the value being
+// parsed. This lets callers tell a truncated document from one that ended cleanly.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// The runes JSON treats as insignificant whitespace between tokens
+const WHITESPACE_RUNES = " \t\n\r"
+
+// IsWhitespace reports whether r is one of WHITESPACE_RUNES, the only whitespace the parser skips.
+func IsWhitespace(r rune) bool {
 	return r == ' ' || r == '\n' || r == '\r' || r == '\t'
 }
 
 func UnmarshalWhitespace(reader *bufio.Reader) error {
+	d := newDecoder(reader)
+	return d.wrapError(d.unmarshalWhitespace())
+}
+
+// skipWhitespace reports whether r is insignificant whitespace. With AllowComments, a / starts a comment, which is
+// consumed and treated as whitespace.
+func (d *Decoder) skipWhitespace(r rune) (bool, error) {
+	if IsWhitespace(r) {
+		return true, nil
+	}
+	if r != '/' || !d.AllowComments {
+		return false, nil
+	}
+	return true, d.skipComment()
+}
+
+// skipComment consumes a // line comment or /* block comment */ whose leading / was already read.
+func (d *Decoder) skipComment() error {
+	r, _, err := d.reader.ReadRune()
+	if err != nil {
+		return fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
+	}
+	if r == '/' {
+		for {
+			r, _, err := d.reader.ReadRune()
+			if err == io.EOF || r == '\n' {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
+			}
+		}
+	} else if r == '*' {
+		star := false
+		for {
+			r, _, err := d.reader.ReadRune()
+			if err == io.EOF {
+				return fmt.Errorf("unterminated block comment")
+			} else if err != nil {
+				return fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
+			}
+			if star && r == '/' {
+				return nil
+			}
+			star = r == '*'
+		}
+	}
+	return fmt.Errorf("invalid comment, expected / or * after /, found: %c", r)
+}
+
+func (d *Decoder) unmarshalWhitespace() error {
 	eof := false
 	for {
-		r, _, err := reader.ReadRune()
+		r, _, err := d.reader.ReadRune()
 		if err == io.EOF {
 			eof = true
 			break
 		} else if err != nil {
-			return fmt.Errorf("failed to read rune: %w", err)
+			return fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
+		}
+		// Having read the three bytes of a byte order mark means it was the first rune of the input
+		if r == BYTE_ORDER_MARK && d.reader.offset == int64(utf8.RuneLen(BYTE_ORDER_MARK)) && !d.DisallowBOM {
+			continue
+		}
+		whitespace, err := d.skipWhitespace(r)
+		if err != nil {
+			return err
 		}
-		if !isJsonWhitespace(r) {
+		if !whitespace {
 			break
 		}
 	}
 	if !eof {
-		if err := reader.UnreadRune(); err != nil {
+		if err := d.reader.UnreadRune(); err != nil {
 			return fmt.Errorf("failed to unread rune: %w", err)
 		}
 	}
@@ -81,6 +257,48 @@ func UnmarshalWhitespace(reader *bufio.Reader) error {
 }
 
 func UnmarshalObject(reader *bufio.Reader) (map[string]interface{}, error) {
+	d := newDecoder(reader)
+	value, err := d.unmarshalObject()
+	if err != nil {
+		return nil, d.wrapError(err)
+	}
+	return value, nil
+}
+
+func (d *Decoder) unmarshalObject() (map[string]interface{}, error) {
+	object := make(map[string]interface{})
+	err := d.unmarshalObjectMembers(func(key string) error {
+		value, err := d.unmarshalValue()
+		if err != nil && value == nil {
+			return err
+		}
+		object[key] = value
+		return err
+	})
+	// On error, object holds the members parsed so far
+	return object, err
+}
+
+func (d *Decoder) unmarshalOrderedObject() (*OrderedObject, error) {
+	object := NewOrderedObject()
+	err := d.unmarshalObjectMembers(func(key string) error {
+		value, err := d.unmarshalValue()
+		if err != nil && value == nil {
+			return err
+		}
+		object.Set(key, value)
+		return err
+	})
+	// On error, object holds the members parsed so far
+	return object, err
+}
+
+// unmarshalObjectMembers parses an object, calling unmarshalMember after each key to consume its value.
+func (d *Decoder) unmarshalObjectMembers(unmarshalMember func(key string) error) error {
+	if err := d.enterNesting(); err != nil {
+		return err
+	}
+	defer d.exitNesting()
 	// States
 	// 0 start
 	// 1 {
@@ -90,150 +308,274 @@ func UnmarshalObject(reader *bufio.Reader) (map[string]interface{}, error) {
 	// 5 { ... key:value,
 	state := 0
 	key := ""
-	object := make(map[string]interface{})
+	policy := d.duplicateKeyPolicy()
+	var seenKeys map[string]bool
+	if policy != DUPLICATE_KEY_LAST_WINS {
+		seenKeys = make(map[string]bool)
+	}
+	duplicate := false
 	for {
-		r, _, err := reader.ReadRune()
-		if err != nil {
-			return nil, fmt.Errorf("failed to read rune: %w", err)
+		r, _, err := d.reader.ReadRune()
+		if err == io.EOF && state == 2 {
+			return fmt.Errorf("unexpected end of input: expected ':' after object key %q: %w", key, io.ErrUnexpectedEOF)
+		} else if err == io.EOF && state == 3 {
+			return fmt.Errorf("unexpected end of input: expected value for object key %q: %w", key, io.ErrUnexpectedEOF)
+		} else if err == io.EOF && state == 4 {
+			return fmt.Errorf("unexpected end of input: expected , or } after value for object key %q: %w", key, io.ErrUnexpectedEOF)
+		} else if err == io.EOF && (state == 1 || state == 5) {
+			return fmt.Errorf("unexpected end of input: expected object key or }: %w", io.ErrUnexpectedEOF)
+		} else if err != nil {
+			return fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
 		}
 		if state == 0 {
 			if r == '{' {
 				state = 1
 			} else {
-				return nil, fmt.Errorf("failed to Unmarshal object: no opening {")
+				return fmt.Errorf("failed to Unmarshal object: no opening {")
 			}
 		} else if state == 1 || state == 5 {
-			if isJsonWhitespace(r) {
+			if whitespace, err := d.skipWhitespace(r); err != nil {
+				return err
+			} else if whitespace {
 				// stay in state 1
-			} else if state == 1 && r == '}' {
+			} else if (state == 1 || d.AllowTrailingComma) && r == '}' {
 				break
 			} else {
-				if err = reader.UnreadRune(); err != nil {
-					return nil, fmt.Errorf("failed to unread rune: %w", err)
+				if err = d.reader.UnreadRune(); err != nil {
+					return fmt.Errorf("failed to unread rune: %w", err)
 				}
-				key, err = UnmarshalString(reader)
+				key, err = d.unmarshalKey()
 				if err != nil {
-					return nil, fmt.Errorf("failed to Unmarshal object key: %w", err)
+					return fmt.Errorf("failed to Unmarshal object key: %w", err)
+				}
+				if seenKeys != nil {
+					duplicate = seenKeys[key]
+					if duplicate && policy == DUPLICATE_KEY_ERROR {
+						return fmt.Errorf("failed to Unmarshal object: duplicate key %s", key)
+					}
+					seenKeys[key] = true
 				}
 				state = 2
 			}
 		} else if state == 2 {
-			if isJsonWhitespace(r) {
+			if whitespace, err := d.skipWhitespace(r); err != nil {
+				return err
+			} else if whitespace {
 				// stay in state 2
 			} else if r == ':' {
 				state = 3
 			} else {
-				return nil, fmt.Errorf("failed to find matching value for object key: %s", key)
+				return fmt.Errorf("failed to find matching value for object key: %s", key)
 			}
 		} else if state == 3 {
-			value, err := UnmarshalValue(reader)
+			if err = d.reader.UnreadRune(); err != nil {
+				return fmt.Errorf("failed to unread rune: %w", err)
+			}
+			d.path = append(d.path, key)
+			if duplicate {
+				// DUPLICATE_KEY_FIRST_WINS: the first value has been stored already
+				_, err = d.unmarshalValue()
+			} else {
+				err = unmarshalMember(key)
+			}
 			if err != nil {
-				return nil, fmt.Errorf("failed to Unmarshal value for object key: %s", key)
+				if !d.recordErrorPath() {
+					return err
+				}
+				return fmt.Errorf("failed to Unmarshal value for object key %s: %w", key, err)
 			}
-			object[key] = value
+			d.path = d.path[:len(d.path)-1]
 			state = 4
 		} else if state == 4 {
-			if r == '}' {
+			if whitespace, err := d.skipWhitespace(r); err != nil {
+				return err
+			} else if whitespace {
+				// stay in state 4
+			} else if r == '}' {
 				break
 			} else if r == ',' {
 				state = 5
+			} else {
+				return fmt.Errorf("failed to Unmarshal object: expected , or } after value for object key %s, found: %c", key, r)
 			}
 		}
 	}
-	return object, nil
+	return nil
 }
 
 func UnmarshalArray(reader *bufio.Reader) ([]interface{}, error) {
+	d := newDecoder(reader)
+	value, err := d.unmarshalArray()
+	if err != nil {
+		return nil, d.wrapError(err)
+	}
+	return value, nil
+}
+
+func (d *Decoder) unmarshalArray() ([]interface{}, error) {
+	var values []interface{}
+	err := d.unmarshalArrayElements(func(index int) error {
+		value, err := d.unmarshalValue()
+		if err != nil && value == nil {
+			return err
+		}
+		values = append(values, value)
+		return err
+	})
+	// On error, values holds the elements parsed so far
+	return values, err
+}
+
+// unmarshalArrayElements parses an array, calling unmarshalElement to consume each value.
+func (d *Decoder) unmarshalArrayElements(unmarshalElement func(index int) error) error {
+	if err := d.enterNesting(); err != nil {
+		return err
+	}
+	defer d.exitNesting()
 	// States
 	// 0 start
 	// 1 start -> [
 	// 2 start -> [ -> 1+ values
+	// 3 start -> [ -> 1+ values -> ,
 	state := 0
-	var values []interface{}
+	index := 0
 	for {
-		r, _, err := reader.ReadRune()
-		if err != nil {
-			return nil, fmt.Errorf("failed to read rune: %w", err)
+		r, _, err := d.reader.ReadRune()
+		if err == io.EOF && state == 1 {
+			return fmt.Errorf("unexpected end of input: array not closed, expected element or ]: %w", io.ErrUnexpectedEOF)
+		} else if err == io.EOF && state == 2 {
+			return fmt.Errorf("unexpected end of input: array not closed, expected , or ] after element %d: %w", index-1, io.ErrUnexpectedEOF)
+		} else if err == io.EOF && state == 3 {
+			return fmt.Errorf("unexpected end of input: array not closed, expected element after ,: %w", io.ErrUnexpectedEOF)
+		} else if err != nil {
+			return fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
 		}
 
 		if state == 0 {
 			if r == '[' {
 				state = 1
 			} else {
-				return nil, fmt.Errorf("failed to Unmarshal array: no opening [")
+				return fmt.Errorf("failed to Unmarshal array: no opening [")
 			}
-		} else if state == 1 {
-			if isJsonWhitespace(r) {
+		} else if state == 1 || state == 3 {
+			if whitespace, err := d.skipWhitespace(r); err != nil {
+				return err
+			} else if whitespace {
 				// stay in state 1
-			} else if r == ']' {
+			} else if (state == 1 || d.AllowTrailingComma) && r == ']' {
 				break
 			} else {
-				if err = reader.UnreadRune(); err != nil {
-					return nil, fmt.Errorf("failed to unread rune: %w", err)
+				if err = d.reader.UnreadRune(); err != nil {
+					return fmt.Errorf("failed to unread rune: %w", err)
 				}
-				value, err := UnmarshalValue(reader)
-				if err != nil {
-					return nil, fmt.Errorf("failed to Unmarshal array: %w", err)
+				d.path = append(d.path, index)
+				if err = unmarshalElement(index); err != nil {
+					if !d.recordErrorPath() {
+						return err
+					}
+					return fmt.Errorf("failed to Unmarshal array: %w", err)
 				}
-				values = append(values, value)
+				d.path = d.path[:len(d.path)-1]
+				index += 1
 				state = 2
 			}
 		} else if state == 2 {
-			if r == ',' {
-				state = 1
+			if whitespace, err := d.skipWhitespace(r); err != nil {
+				return err
+			} else if whitespace {
+				// stay in state 2
+			} else if r == ',' {
+				state = 3
 			} else if r == ']' {
 				break
 			} else {
-				return nil, fmt.Errorf("failed to Unmarshal array: no , or ]")
+				return fmt.Errorf("failed to Unmarshal array: no , or ]")
 			}
 		}
 	}
-	return values, nil
+	return nil
 }
 
 func UnmarshalNull(reader *bufio.Reader) (interface{}, error) {
-	var value [4]byte
-	n, err := reader.Read(value[:])
+	d := newDecoder(reader)
+	value, err := d.unmarshalNull()
 	if err != nil {
-		return false, fmt.Errorf("failed to read chars while parsing null: %w", err)
-	} else if n != 4 {
-		return false, fmt.Errorf("failed to read all 4 chars while parsing null, could only read %d chars", n)
-	}
-	if string(value[:]) != NULL_STRING {
-		return nil, fmt.Errorf("could not Unmarshal null, found: %s", value)
+		return nil, d.wrapError(err)
 	}
-	return nil, nil
+	return value, nil
+}
+
+func (d *Decoder) unmarshalNull() (interface{}, error) {
+	return nil, d.unmarshalLiteral(NULL_STRING)
 }
 
 func UnmarshalTrue(reader *bufio.Reader) (bool, error) {
-	var value [4]byte
-	n, err := reader.Read(value[:])
+	d := newDecoder(reader)
+	value, err := d.unmarshalTrue()
 	if err != nil {
-		return false, fmt.Errorf("failed to read 4 chars while parsing true: %w", err)
-	} else if n != len(value) {
-		return false, fmt.Errorf("failed to read all 4 chars while parsing true, could only read %d chars", n)
+		return false, d.wrapError(err)
 	}
-	if string(value[:]) != TRUE_STRING {
-		return false, fmt.Errorf("could not Unmarshal true, found: %s", value)
+	return value, nil
+}
+
+func (d *Decoder) unmarshalTrue() (bool, error) {
+	if err := d.unmarshalLiteral(TRUE_STRING); err != nil {
+		return false, err
 	}
 	return true, nil
 }
 
 func UnmarshalFalse(reader *bufio.Reader) (bool, error) {
-	var value [5]byte
-	n, err := reader.Read(value[:])
+	d := newDecoder(reader)
+	value, err := d.unmarshalFalse()
 	if err != nil {
-		return false, fmt.Errorf("failed to read 5 chars while parsing false: %w", err)
-	} else if n != len(value) {
-		return false, fmt.Errorf("failed to read all 5 chars while parsing false, could only read %d chars", n)
+		return false, d.wrapError(err)
 	}
-	if string(value[:]) != FALSE_STRING {
-		return false, fmt.Errorf("could not Unmarshal false, found: %s", value)
+	return value, nil
+}
+
+func (d *Decoder) unmarshalFalse() (bool, error) {
+	return false, d.unmarshalLiteral(FALSE_STRING)
+}
+
+// unmarshalLiteral consumes literal one rune at a time. The first rune that does not match is unread, so it is left
+// for whatever parses the input next.
+func (d *Decoder) unmarshalLiteral(literal string) error {
+	for i, expected := range literal {
+		r, _, err := d.reader.ReadRune()
+		if err == io.EOF {
+			return fmt.Errorf("unexpected end of input in literal, expected %s: %w", literal, io.ErrUnexpectedEOF)
+		} else if err != nil {
+			return fmt.Errorf("failed to read rune: %w", err)
+		}
+		if r != expected {
+			if err := d.reader.UnreadRune(); err != nil {
+				return fmt.Errorf("failed to unread rune: %w", err)
+			}
+			return fmt.Errorf("invalid literal, expected %s, found: %s", literal, literal[:i]+string(r))
+		}
 	}
-	return false, nil
+	return nil
 }
 
 func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
+	d := newDecoder(reader)
+	value, err := d.unmarshalNumber()
+	if err != nil {
+		return nil, d.wrapError(err)
+	}
+	return value, nil
+}
+
+func (d *Decoder) unmarshalNumber() (interface{}, error) {
+	return d.unmarshalNumberAs(d.numberMode())
+}
+
+// unmarshalNumberAs parses a number and converts it to the type chosen by mode.
+func (d *Decoder) unmarshalNumberAs(mode NumberMode) (interface{}, error) {
+	if value, ok := d.unmarshalBufferedInteger(mode); ok {
+		return value, nil
+	}
 	// States (https://www.json.org/json-en.html)
 	// 0 start
 	// 1 start -> -
@@ -250,15 +592,16 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 	eof := false
 	validEnd := false
 	invalidTransition := false
+	leadingZero := false
 	var numberBuf strings.Builder
 	for {
-		r, _, err := reader.ReadRune()
+		r, _, err := d.reader.ReadRune()
 		if err == io.EOF {
 			eof = true
 		} else if err != nil {
-			return 0, fmt.Errorf("failed to read rune: %w", err)
+			return 0, fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
 		}
-		isDigit := unicode.IsDigit(r)
+		digit := isDigit(r)
 
 		switch state {
 		case 0:
@@ -268,7 +611,7 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 				state = 1
 			} else if r == '0' {
 				state = 2
-			} else if isDigit {
+			} else if digit {
 				state = 3
 			} else {
 				invalidTransition = true
@@ -278,7 +621,7 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 				invalidTransition = true
 			} else if r == '0' {
 				state = 2
-			} else if isDigit {
+			} else if digit {
 				state = 3
 			} else {
 				invalidTransition = true
@@ -286,6 +629,9 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 		case 2:
 			if eof {
 				validEnd = true
+			} else if digit {
+				leadingZero = true
+				invalidTransition = true
 			} else if r == '.' {
 				state = 5
 			} else if r == 'e' || r == 'E' {
@@ -298,7 +644,7 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 		case 4:
 			if eof {
 				validEnd = true
-			} else if isDigit {
+			} else if digit {
 				state = 4
 			} else if r == '.' {
 				state = 5
@@ -310,7 +656,7 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 		case 5:
 			if eof {
 				invalidTransition = true
-			} else if isDigit {
+			} else if digit {
 				state = 6
 			} else {
 				invalidTransition = true
@@ -318,7 +664,7 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 		case 6:
 			if eof {
 				validEnd = true
-			} else if isDigit {
+			} else if digit {
 				state = 6
 			} else if r == 'e' || r == 'E' {
 				state = 7
@@ -330,7 +676,7 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 				invalidTransition = true
 			} else if r == '-' || r == '+' {
 				state = 8
-			} else if isDigit {
+			} else if digit {
 				state = 9
 			} else {
 				invalidTransition = true
@@ -338,34 +684,111 @@ func UnmarshalNumber(reader *bufio.Reader) (interface{}, error) {
 		case 8:
 			if eof {
 				invalidTransition = true
-			} else if isDigit {
+			} else if digit {
 				state = 9
 			} else {
 				invalidTransition = true
 			}
 		case 9:
-			if eof || !isDigit {
+			if eof || !digit {
 				validEnd = true
 			}
 		}
-		if !validEnd {
+		if !validEnd && !eof {
 			numberBuf.WriteRune(r)
 		}
 		if validEnd || invalidTransition {
 			break
 		}
 	}
-	if invalidTransition {
+	if leadingZero {
+		// Consume the remaining digits so the error reports the whole token
+		for {
+			r, _, err := d.reader.ReadRune()
+			if err != nil {
+				break
+			}
+			if !isDigit(r) {
+				if err := d.reader.UnreadRune(); err != nil {
+					return 0, fmt.Errorf("failed to unread rune: %w", err)
+				}
+				break
+			}
+			numberBuf.WriteRune(r)
+		}
+		return 0, fmt.Errorf("leading zeros are not allowed in number: %s", numberBuf.String())
+	}
+	if invalidTransition && eof {
+		return 0, fmt.Errorf("incomplete number %s: %w", numberBuf.String(), io.ErrUnexpectedEOF)
+	} else if invalidTransition {
 		return 0, fmt.Errorf("invalid char in number: %s", numberBuf.String())
 	}
 	if !eof {
-		if err := reader.UnreadRune(); err != nil {
+		if err := d.reader.UnreadRune(); err != nil {
 			return 0, fmt.Errorf("failed to unread rune: %w", err)
 		}
 	}
+	switch mode {
+	case NUMBER_MODE_NUMBER:
+		return Number(numberBuf.String()), nil
+	case NUMBER_MODE_FLOAT64:
+		float64Value, err := strconv.ParseFloat(numberBuf.String(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to Unmarshal float number: %w", err)
+		}
+		return float64Value, nil
+	}
 	return convertToNumber(numberBuf.String())
 }
 
+// unmarshalBufferedInteger parses an integer straight out of the reader's buffer, avoiding the rune-by-rune state
+// machine. It reports false without consuming anything when the number is not a plain int64 that ends inside the
+// buffer, leaving it to the general path.
+func (d *Decoder) unmarshalBufferedInteger(mode NumberMode) (interface{}, bool) {
+	buf := d.reader.buffered()
+	i := 0
+	negative := len(buf) > 0 && buf[0] == '-'
+	if negative {
+		i += 1
+	}
+	start := i
+	var value int64
+	for ; i < len(buf) && '0' <= buf[i] && buf[i] <= '9'; i++ {
+		digit := int64(buf[i] - '0')
+		// Accumulate negatively so that the minimum int64 fits
+		if value < (math.MinInt64+digit)/10 {
+			return nil, false
+		}
+		value = value*10 - digit
+	}
+	digits := i - start
+	if digits == 0 || i == len(buf) || (digits > 1 && buf[start] == '0') {
+		return nil, false
+	}
+	if buf[i] == '.' || buf[i] == 'e' || buf[i] == 'E' || buf[i] >= utf8.RuneSelf {
+		return nil, false
+	}
+	if !negative {
+		if value == math.MinInt64 {
+			return nil, false
+		}
+		value = -value
+	}
+	var number interface{} = value
+	if mode == NUMBER_MODE_NUMBER {
+		number = Number(buf[:i])
+	} else if mode == NUMBER_MODE_FLOAT64 && negative && value == 0 {
+		// float64(value) would lose the sign, which ParseFloat keeps on the general path
+		number = math.Copysign(0, -1)
+	} else if mode == NUMBER_MODE_FLOAT64 {
+		number = float64(value)
+	}
+	if err := d.reader.discard(i, i); err != nil {
+		return nil, false
+	}
+	return number, true
+}
+
 func convertToNumber(numberString string) (interface{}, error) {
 	if strings.ContainsAny(numberString, ".eE") {
 		float64Value, err := strconv.ParseFloat(numberString, 64)
@@ -375,22 +798,41 @@ func convertToNumber(numberString string) (interface{}, error) {
 		return float64Value, nil
 	}
 	int64Value, err := strconv.ParseInt(numberString, 10, 64)
-	if err != nil {
+	if errors.Is(err, strconv.ErrRange) {
+		// Integers too large for int64 lose precision as float64 rather than failing
+		float64Value, err := strconv.ParseFloat(numberString, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to Unmarshal integer number: %w", err)
+		}
+		return float64Value, nil
+	} else if err != nil {
 		return 0, fmt.Errorf("failed to Unmarshal integer number: %w", err)
 	}
 	return int64Value, nil
 }
 
+// isDigit reports whether r is an ASCII digit. JSON numbers allow no other digits.
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
 // serializeUnicode returns the unicode character given the code points in reader. Expects 4 hex digits.
-func convertHexToUnicode(reader *bufio.Reader) (rune, error) {
+func (d *Decoder) convertHexToUnicode() (rune, error) {
 	var hexChars [4]byte
-	n, err := reader.Read(hexChars[:])
-	if n != 4 {
+	if _, err := io.ReadFull(d.reader, hexChars[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
 		return 0, UNICODE_INSUFFICIENT_BYTES
-	}
-	if err != nil {
+	} else if err != nil {
 		return 0, fmt.Errorf("failed to read hex chars for unicode: %w", err)
 	}
+	for _, c := range hexChars {
+		if !isHexDigit(c) {
+			return 0, fmt.Errorf("invalid hex digit %q in \\u escape", c)
+		}
+	}
 	hexString := string(hexChars[:])
 	hexValue, err := strconv.ParseInt(hexString, 16, 32)
 	if err != nil {
@@ -399,11 +841,42 @@ func convertHexToUnicode(reader *bufio.Reader) (rune, error) {
 	return rune(hexValue), nil
 }
 
+// convertSurrogatePair reads the \uXXXX low surrogate that must follow high and returns the combined code point.
+func (d *Decoder) convertSurrogatePair(high rune) (rune, error) {
+	var escape [2]byte
+	if _, err := io.ReadFull(d.reader, escape[:]); err != nil {
+		return 0, fmt.Errorf("failed to read escape for low surrogate: %w", unexpectedEOF(err))
+	}
+	if escape[0] != '\\' || escape[1] != 'u' {
+		return 0, fmt.Errorf("high surrogate %U is not followed by a \\u escape", high)
+	}
+	low, err := d.convertHexToUnicode()
+	if err != nil {
+		return 0, err
+	}
+	if low < 0xDC00 || low > 0xDFFF {
+		return 0, fmt.Errorf("high surrogate %U is followed by %U, which is not a low surrogate", high, low)
+	}
+	return utf16.DecodeRune(high, low), nil
+}
+
 func UnmarshalString(reader *bufio.Reader) (string, error) {
+	d := newDecoder(reader)
+	value, err := d.unmarshalString()
+	if err != nil {
+		return "", d.wrapError(err)
+	}
+	return value, nil
+}
+
+func (d *Decoder) unmarshalString() (string, error) {
+	if value, ok := d.unmarshalBufferedString(false); ok {
+		return value, nil
+	}
 	// Verify that the first char is a double quote
-	r, _, err := reader.ReadRune()
+	r, _, err := d.reader.ReadRune()
 	if err != nil {
-		return "", fmt.Errorf("failed to read rune: %w", err)
+		return "", fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
 	}
 	if r != '"' {
 		return "", fmt.Errorf("cannot match string, no opening double quote found: %c", r)
@@ -411,9 +884,16 @@ func UnmarshalString(reader *bufio.Reader) (string, error) {
 	var b strings.Builder
 	backslash := false
 	for {
-		r, _, err := reader.ReadRune()
+		if d.MaxStringLen > 0 && b.Len() > d.MaxStringLen {
+			return "", fmt.Errorf("string exceeds %d byte limit", d.MaxStringLen)
+		}
+		r, size, err := d.reader.ReadRune()
 		if err != nil {
-			return "", fmt.Errorf("failed to read rune: %w", err)
+			return "", fmt.Errorf("failed to read rune: %w", unexpectedEOF(err))
+		}
+		// ReadRune returns U+FFFD with size 1 for an invalid byte, and size 3 for a U+FFFD in the input
+		if r == utf8.RuneError && size == 1 && d.StrictUTF8 {
+			return "", fmt.Errorf("invalid UTF-8 in string")
 		}
 		// Handle escaped characters
 		if backslash {
@@ -435,13 +915,22 @@ func UnmarshalString(reader *bufio.Reader) (string, error) {
 			case 't':
 				b.WriteRune('\t')
 			case 'u':
-				unicodeChar, err := convertHexToUnicode(reader)
+				unicodeChar, err := d.convertHexToUnicode()
 				if err != nil {
-					return "", fmt.Errorf("failed to Unmarshal unicode character")
+					return "", fmt.Errorf("failed to Unmarshal unicode character: %w", err)
+				}
+				if unicodeChar >= 0xD800 && unicodeChar <= 0xDBFF {
+					unicodeChar, err = d.convertSurrogatePair(unicodeChar)
+					if err != nil {
+						return "", fmt.Errorf("failed to Unmarshal surrogate pair: %w", err)
+					}
 				}
 				b.WriteRune(unicodeChar)
 			default:
-				return "", fmt.Errorf("error: unexpected escape character %c", r)
+				if !d.LenientEscapes {
+					return "", fmt.Errorf("error: unexpected escape character %c", r)
+				}
+				b.WriteRune(r)
 			}
 			backslash = false
 		} else {
@@ -449,6 +938,8 @@ func UnmarshalString(reader *bufio.Reader) (string, error) {
 				backslash = true
 			} else if r == '"' {
 				break
+			} else if r < 0x20 && !d.AllowControlCharacters {
+				return "", fmt.Errorf("unescaped control character %U in string", r)
 			} else {
 				b.WriteRune(r)
 			}
@@ -457,6 +948,77 @@ func UnmarshalString(reader *bufio.Reader) (string, error) {
 	return b.String(), nil
 }
 
+// Object keys up to this many bytes are interned, up to this many distinct keys per Decoder
+const MAX_INTERNED_KEY_LENGTH = 64
+const MAX_INTERNED_KEYS = 256
+
+// unmarshalKey is like unmarshalString but interns the keys it reads, so the keys repeated across the objects of a
+// document share a single allocation.
+func (d *Decoder) unmarshalKey() (string, error) {
+	if value, ok := d.unmarshalBufferedString(true); ok {
+		return value, nil
+	}
+	return d.unmarshalString()
+}
+
+// unmarshalBufferedString parses a string straight out of the reader's buffer when it has no escapes or control
+// characters, is valid UTF-8, and ends inside the buffer. Otherwise it reports false without consuming anything,
+// leaving the string to the rune-by-rune path.
+func (d *Decoder) unmarshalBufferedString(intern bool) (string, bool) {
+	buf := d.reader.buffered()
+	if len(buf) == 0 || buf[0] != '"' {
+		return "", false
+	}
+	ascii := true
+	for i := 1; i < len(buf); i++ {
+		if d.MaxStringLen > 0 && i-1 > d.MaxStringLen {
+			// Leave the error to unmarshalString
+			return "", false
+		}
+		c := buf[i]
+		if c == '"' {
+			raw := buf[1:i]
+			runes := len(raw)
+			if !ascii {
+				if !utf8.Valid(raw) {
+					return "", false
+				}
+				runes = utf8.RuneCount(raw)
+			}
+			var value string
+			if intern {
+				value = d.internKey(raw)
+			} else {
+				value = string(raw)
+			}
+			// Include the quotes
+			if err := d.reader.discard(i+1, runes+2); err != nil {
+				return "", false
+			}
+			return value, true
+		} else if c == '\\' || c < 0x20 {
+			return "", false
+		} else if c >= utf8.RuneSelf {
+			ascii = false
+		}
+	}
+	return "", false
+}
+
+func (d *Decoder) internKey(raw []byte) string {
+	if key, ok := d.keys[string(raw)]; ok {
+		return key
+	}
+	key := string(raw)
+	if len(raw) <= MAX_INTERNED_KEY_LENGTH && len(d.keys) < MAX_INTERNED_KEYS {
+		if d.keys == nil {
+			d.keys = make(map[string]string)
+		}
+		d.keys[key] = key
+	}
+	return key
+}
+
 func Serialize(reader bufio.Reader) (interface{}, error) {
 
 	return nil, nil
@@ -0,0 +1,504 @@
+package json
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Unmarshaler is implemented by types that parse their own JSON encoding. The typed decoder passes
+// UnmarshalJSONValue the raw bytes of the value, without surrounding whitespace.
+type Unmarshaler interface {
+	UnmarshalJSONValue(data []byte) error
+}
+
+// UnmarshalInto parses a JSON value from reader and stores it in the value pointed to by target.
+// Object keys are matched case-insensitively to exported struct fields, named by their json tag when they have one.
+func UnmarshalInto(reader *bufio.Reader, target interface{}) error {
+	return newDecoder(reader).decodeInto(target, false)
+}
+
+// DecodeInto reads the next JSON value from the input and stores it in the value pointed to by target. Like Decode, it
+// may be called repeatedly to read a stream of values, and returns io.EOF, unwrapped, once only whitespace remains.
+func (d *Decoder) DecodeInto(target interface{}) error {
+	return d.decodeInto(target, true)
+}
+
+// decodeInto implements DecodeInto. Unless stream is set, input holding no value is the EMPTY_INPUT error rather than
+// io.EOF.
+func (d *Decoder) decodeInto(target interface{}, stream bool) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Pointer || targetValue.IsNil() {
+		return fmt.Errorf("cannot Unmarshal into non-pointer or nil target of type %T", target)
+	}
+	d.reader.setLimit(d.MaxBytes)
+	if err := d.prepareTokenForDecode(); err != nil {
+		return d.wrapError(err)
+	}
+	if stream && len(d.tokenStack) == 0 {
+		eof, err := d.atEOF()
+		if err != nil {
+			return d.wrapError(err)
+		}
+		if eof {
+			return io.EOF
+		}
+	}
+	if err := d.unmarshalInto(targetValue.Elem()); err != nil {
+		return d.wrapError(err)
+	}
+	d.tokenValueEnd()
+	return nil
+}
+
+// RegisterTypeDecoder makes DecodeInto decode values into targets of type t by calling fn with the raw bytes of the
+// value, without surrounding whitespace, and the target to set. It takes precedence over every other way of decoding
+// t, including Unmarshaler, and is called for null too. A nil fn removes the registration.
+func (d *Decoder) RegisterTypeDecoder(t reflect.Type, fn func(raw []byte, target reflect.Value) error) {
+	if fn == nil {
+		delete(d.typeDecoders, t)
+		return
+	}
+	if d.typeDecoders == nil {
+		d.typeDecoders = make(map[reflect.Type]func([]byte, reflect.Value) error)
+	}
+	d.typeDecoders[t] = fn
+}
+
+func (d *Decoder) unmarshalInto(target reflect.Value) error {
+	if err := d.unmarshalWhitespace(); err != nil {
+		return fmt.Errorf("failed to Unmarshal leading whitespace: %w", err)
+	}
+	r, err := d.peekRune()
+	if errors.Is(err, io.EOF) && d.depth == 0 {
+		return EMPTY_INPUT
+	} else if errors.Is(err, io.EOF) {
+		return fmt.Errorf("expected value: %w", io.ErrUnexpectedEOF)
+	} else if err != nil {
+		return err
+	}
+	if typeDecoder, ok := d.typeDecoders[target.Type()]; ok {
+		data, err := d.captureValue()
+		if err != nil {
+			return err
+		}
+		if err := typeDecoder(data, target); err != nil {
+			return fmt.Errorf("failed to Unmarshal %s with its registered decoder: %w", target.Type(), err)
+		}
+		return nil
+	}
+	if target.Kind() != reflect.Pointer && target.CanAddr() {
+		if unmarshaler, ok := target.Addr().Interface().(Unmarshaler); ok {
+			data, err := d.captureValue()
+			if err != nil {
+				return err
+			}
+			if err := unmarshaler.UnmarshalJSONValue(data); err != nil {
+				return fmt.Errorf("failed to Unmarshal %s with UnmarshalJSONValue: %w", target.Type(), err)
+			}
+			return nil
+		}
+	}
+	if r == 'n' {
+		return d.unmarshalNullInto(target)
+	}
+	if target.Type() == timeType {
+		return d.unmarshalTime(target)
+	}
+	if target.Kind() != reflect.Pointer && target.CanAddr() {
+		if textUnmarshaler, ok := target.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return d.unmarshalText(textUnmarshaler, target.Type())
+		}
+	}
+	switch {
+	case target.Kind() == reflect.Pointer:
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		return d.unmarshalInto(target.Elem())
+	case target.Kind() == reflect.Interface && target.NumMethod() == 0:
+		value, err := d.unmarshalValue()
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			target.Set(reflect.Zero(target.Type()))
+		} else {
+			target.Set(reflect.ValueOf(value))
+		}
+		return nil
+	case r == '{' && target.Kind() == reflect.Struct:
+		err = d.unmarshalStruct(target)
+	case r == '{' && target.Kind() == reflect.Map:
+		err = d.unmarshalMap(target)
+	case r == '{':
+		return fmt.Errorf("cannot Unmarshal object into Go value of type %s", target.Type())
+	case r == '[' && target.Kind() == reflect.Slice:
+		err = d.unmarshalSlice(target)
+	case r == '[' && target.Kind() == reflect.Array:
+		err = d.unmarshalFixedArray(target)
+	case r == '[':
+		return fmt.Errorf("cannot Unmarshal array into Go value of type %s", target.Type())
+	case target.Kind() == reflect.Bool && d.LenientBools:
+		err = d.unmarshalLenientBool(r, target)
+	case isDigit(r) || r == '-':
+		// Keep the exact text so that integer targets are parsed from it rather than from a rounded float64
+		var number interface{}
+		number, err = d.unmarshalNumberAs(NUMBER_MODE_NUMBER)
+		if err == nil {
+			err = assignScalar(number, target)
+		}
+	default:
+		value, err := d.unmarshalValue()
+		if err != nil {
+			return err
+		}
+		return assignScalar(value, target)
+	}
+	if err != nil {
+		return err
+	}
+	if err := d.unmarshalWhitespace(); err != nil {
+		return fmt.Errorf("failed to Unmarshal trailing whitespace: %w", err)
+	}
+	return nil
+}
+
+// unmarshalLenientBool stores a JSON bool, the number 0 or 1, or the string "false" or "true" in a bool target.
+func (d *Decoder) unmarshalLenientBool(r rune, target reflect.Value) error {
+	var value interface{}
+	var err error
+	if isDigit(r) || r == '-' {
+		value, err = d.unmarshalNumberAs(NUMBER_MODE_NUMBER)
+	} else {
+		value, err = d.unmarshalValue()
+	}
+	if err != nil {
+		return err
+	}
+	switch value {
+	case Number("0"), FALSE_STRING:
+		target.SetBool(false)
+		return nil
+	case Number("1"), TRUE_STRING:
+		target.SetBool(true)
+		return nil
+	}
+	return assignScalar(value, target)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// unmarshalTime parses an RFC 3339 string into a time.Time target.
+func (d *Decoder) unmarshalTime(target reflect.Value) error {
+	value, err := d.unmarshalValue()
+	if err != nil {
+		return err
+	}
+	timeString, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("cannot Unmarshal %s into Go value of type time.Time", jsonTypeName(value))
+	}
+	t, err := time.Parse(time.RFC3339, timeString)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q as an RFC 3339 time: %w", timeString, err)
+	}
+	target.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// unmarshalText passes the contents of a string to a target implementing encoding.TextUnmarshaler.
+func (d *Decoder) unmarshalText(textUnmarshaler encoding.TextUnmarshaler, targetType reflect.Type) error {
+	value, err := d.unmarshalValue()
+	if err != nil {
+		return err
+	}
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("cannot Unmarshal %s into Go value of type %s", jsonTypeName(value), targetType)
+	}
+	if err := textUnmarshaler.UnmarshalText([]byte(text)); err != nil {
+		return fmt.Errorf("failed to Unmarshal %s with UnmarshalText: %w", targetType, err)
+	}
+	return nil
+}
+
+// unmarshalNullInto consumes a null, which sets pointers, interfaces, maps, and slices to nil and leaves other values
+// unchanged.
+func (d *Decoder) unmarshalNullInto(target reflect.Value) error {
+	if _, err := d.unmarshalValue(); err != nil {
+		return err
+	}
+	switch target.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		target.Set(reflect.Zero(target.Type()))
+	}
+	return nil
+}
+
+// captureValue consumes the next value and returns its raw bytes without surrounding whitespace.
+func (d *Decoder) captureValue() ([]byte, error) {
+	if err := d.unmarshalWhitespace(); err != nil {
+		return nil, fmt.Errorf("failed to Unmarshal leading whitespace: %w", err)
+	}
+	// Stop the capture before the trailing whitespace so a trailing comment is not part of the value
+	d.reader.startCapture()
+	_, err := d.unmarshalBareValue()
+	data := d.reader.stopCapture()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.unmarshalWhitespace(); err != nil {
+		return nil, fmt.Errorf("failed to Unmarshal trailing whitespace: %w", err)
+	}
+	return data, nil
+}
+
+func (d *Decoder) unmarshalStruct(target reflect.Value) error {
+	structType := target.Type()
+	remaining, hasRemaining := remainingField(structType)
+	if hasRemaining && (remaining.typ.Kind() != reflect.Map || remaining.typ.Key().Kind() != reflect.String) {
+		return fmt.Errorf("cannot collect remaining members of %s in field %s of type %s, it must be a map with string keys",
+			structType, remaining.name, remaining.typ)
+	}
+	return d.unmarshalObjectMembers(func(key string) error {
+		field, ok := findField(structType, key)
+		if !ok {
+			if d.DisallowUnknownFields {
+				return fmt.Errorf("unknown field %q in %s", key, structType)
+			}
+			if hasRemaining {
+				return d.unmarshalRemaining(target.FieldByIndex(remaining.index), key)
+			}
+			// Unknown keys are parsed and discarded
+			_, err := d.unmarshalValue()
+			return err
+		}
+		value, ok := fieldByIndex(target, field.index, true)
+		if !ok {
+			return fmt.Errorf("cannot set field %s of %s through a nil embedded pointer to an unexported struct", field.name, structType)
+		}
+		if err := d.unmarshalInto(value); err != nil && !d.nestedErrorRecorded() {
+			return fmt.Errorf("failed to Unmarshal field %s of %s: %w", field.name, structType, err)
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// unmarshalRemaining decodes the value of a key that matched no struct field into the struct's remaining field.
+func (d *Decoder) unmarshalRemaining(target reflect.Value, key string) error {
+	mapType := target.Type()
+	if target.IsNil() {
+		target.Set(reflect.MakeMap(mapType))
+	}
+	element := reflect.New(mapType.Elem()).Elem()
+	if err := d.unmarshalInto(element); err != nil && !d.nestedErrorRecorded() {
+		return fmt.Errorf("failed to Unmarshal value of %s: %w", mapType, err)
+	} else if err != nil {
+		return err
+	}
+	target.SetMapIndex(reflect.ValueOf(key).Convert(mapType.Key()), element)
+	return nil
+}
+
+// unmarshalMap decodes an object into a map with string-kinded keys, converting each value to the element type.
+func (d *Decoder) unmarshalMap(target reflect.Value) error {
+	mapType := target.Type()
+	if mapType.Key().Kind() != reflect.String {
+		return fmt.Errorf("cannot Unmarshal object into map with key type %s", mapType.Key())
+	}
+	if target.IsNil() {
+		target.Set(reflect.MakeMap(mapType))
+	}
+	return d.unmarshalObjectMembers(func(key string) error {
+		element := reflect.New(mapType.Elem()).Elem()
+		if err := d.unmarshalInto(element); err != nil && !d.nestedErrorRecorded() {
+			return fmt.Errorf("failed to Unmarshal value of %s: %w", mapType, err)
+		} else if err != nil {
+			return err
+		}
+		target.SetMapIndex(reflect.ValueOf(key).Convert(mapType.Key()), element)
+		return nil
+	})
+}
+
+func (d *Decoder) unmarshalSlice(target reflect.Value) error {
+	slice := reflect.MakeSlice(target.Type(), 0, 0)
+	err := d.unmarshalArrayElements(func(index int) error {
+		slice = reflect.Append(slice, reflect.Zero(target.Type().Elem()))
+		if err := d.unmarshalInto(slice.Index(index)); err != nil && !d.nestedErrorRecorded() {
+			return fmt.Errorf("failed to Unmarshal element %d: %w", index, err)
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	target.Set(slice)
+	return nil
+}
+
+// unmarshalFixedArray fills a Go array from a JSON array, zeroing elements the JSON array is too short to reach.
+// Extra JSON elements are an error unless TruncateArrays is set, in which case they are discarded.
+func (d *Decoder) unmarshalFixedArray(target reflect.Value) error {
+	length := target.Len()
+	count := 0
+	err := d.unmarshalArrayElements(func(index int) error {
+		count += 1
+		if index >= length {
+			if !d.TruncateArrays {
+				return fmt.Errorf("array has more than %d elements for Go value of type %s", length, target.Type())
+			}
+			_, err := d.unmarshalValue()
+			return err
+		}
+		if err := d.unmarshalInto(target.Index(index)); err != nil && !d.nestedErrorRecorded() {
+			return fmt.Errorf("failed to Unmarshal element %d: %w", index, err)
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := count; i < length; i++ {
+		target.Index(i).Set(reflect.Zero(target.Type().Elem()))
+	}
+	return nil
+}
+
+// assignInteger parses number exactly into an integer target. Integral numbers written with a fraction or exponent,
+// such as 1e3 or 2.0, are accepted, and numbers out of the target's range are an error rather than being rounded.
+func assignInteger(number Number, target reflect.Value) error {
+	text, ok := integerText(string(number))
+	if !ok {
+		// Either a fractional part or more digits than any integer type holds
+		value, err := strconv.ParseFloat(string(number), 64)
+		if errors.Is(err, strconv.ErrRange) || err == nil && value == math.Trunc(value) {
+			return fmt.Errorf("number %s overflows Go value of type %s", number, target.Type())
+		}
+		return fmt.Errorf("cannot Unmarshal number %s into Go value of type %s", number, target.Type())
+	}
+	switch target.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil || target.OverflowInt(value) {
+			return fmt.Errorf("number %s overflows Go value of type %s", number, target.Type())
+		}
+		target.SetInt(value)
+	default:
+		value, err := strconv.ParseUint(text, 10, 64)
+		if err != nil || target.OverflowUint(value) {
+			return fmt.Errorf("number %s overflows Go value of type %s", number, target.Type())
+		}
+		target.SetUint(value)
+	}
+	return nil
+}
+
+// assignScalar stores a string, number, bool, or null produced by UnmarshalValue in target, which may be any bool,
+// string, integer, or float type, or a byte slice for a base64 string. A JSON type that does not fit target is an
+// error, as is a number out of its range.
+func assignScalar(value interface{}, target reflect.Value) error {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case Number:
+		if target.Type() == reflect.TypeOf(value) {
+			target.Set(reflect.ValueOf(value))
+			return nil
+		}
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return assignInteger(value, target)
+		}
+		converted, err := convertToNumber(string(value))
+		if err != nil {
+			return err
+		}
+		return assignScalar(converted, target)
+	case string:
+		if target.Kind() == reflect.String {
+			target.SetString(value)
+			return nil
+		}
+		if target.Kind() == reflect.Slice && target.Type().Elem().Kind() == reflect.Uint8 {
+			data, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("failed to decode base64 string into %s: %w", target.Type(), err)
+			}
+			target.SetBytes(data)
+			return nil
+		}
+	case bool:
+		if target.Kind() == reflect.Bool {
+			target.SetBool(value)
+			return nil
+		}
+	case int64:
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if target.OverflowInt(value) {
+				return fmt.Errorf("number %d overflows Go value of type %s", value, target.Type())
+			}
+			target.SetInt(value)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if value < 0 || target.OverflowUint(uint64(value)) {
+				return fmt.Errorf("number %d overflows Go value of type %s", value, target.Type())
+			}
+			target.SetUint(uint64(value))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			target.SetFloat(float64(value))
+			return nil
+		}
+	case float64:
+		switch target.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if target.OverflowFloat(value) {
+				return fmt.Errorf("number %v overflows Go value of type %s", value, target.Type())
+			}
+			target.SetFloat(value)
+			return nil
+		}
+	}
+	name := jsonTypeName(value)
+	if name == "number" {
+		// Name the number too, since it may be the value rather than the type that does not fit
+		name = fmt.Sprintf("number %v", value)
+	}
+	return fmt.Errorf("cannot Unmarshal %s into Go value of type %s", name, target.Type())
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int64, float64, Number:
+		return "number"
+	case map[string]interface{}, *OrderedObject:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,203 @@
+package json
+
+import (
+	"bufio"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func unmarshalIntoString(s string, target interface{}) error {
+	return UnmarshalInto(bufio.NewReader(strings.NewReader(s)), target)
+}
+
+func TestUnmarshalIntoNull(t *testing.T) {
+	number, text := 1, "x"
+	target := struct {
+		Int       *int
+		String    *string
+		Interface interface{}
+		Value     int
+	}{&number, &text, "y", 2}
+	err := unmarshalIntoString(`{"Int": null, "String": null, "Interface": null, "Value": null}`, &target)
+	if err != nil {
+		t.Fatalf("UnmarshalInto failed: %v", err)
+	}
+	if target.Int != nil || target.String != nil || target.Interface != nil {
+		t.Errorf("UnmarshalInto left %v, %v, %v; want nil pointers and a nil interface", target.Int, target.String,
+			target.Interface)
+	}
+	if target.Value != 2 {
+		t.Errorf("UnmarshalInto changed a value field to %d on null; want it left at 2", target.Value)
+	}
+}
+
+func TestUnmarshalIntoSlices(t *testing.T) {
+	var ints []int
+	if err := unmarshalIntoString(`[1, -2, 3]`, &ints); err != nil || !reflect.DeepEqual(ints, []int{1, -2, 3}) {
+		t.Errorf("UnmarshalInto []int = %v, %v", ints, err)
+	}
+	var strs []string
+	if err := unmarshalIntoString(`["a", "b"]`, &strs); err != nil || !reflect.DeepEqual(strs, []string{"a", "b"}) {
+		t.Errorf("UnmarshalInto []string = %v, %v", strs, err)
+	}
+	if err := unmarshalIntoString(`[1, "b"]`, &ints); err == nil {
+		t.Error("UnmarshalInto []int accepted a string element")
+	}
+}
+
+func TestUnmarshalIntoFixedArrays(t *testing.T) {
+	array := [3]int{7, 8, 9}
+	if err := unmarshalIntoString(`[1, 2]`, &array); err != nil || array != [3]int{1, 2, 0} {
+		t.Errorf("UnmarshalInto [3]int from two elements = %v, %v; want [1 2 0]", array, err)
+	}
+	if err := unmarshalIntoString(`[1, 2, 3]`, &array); err != nil || array != [3]int{1, 2, 3} {
+		t.Errorf("UnmarshalInto [3]int from three elements = %v, %v", array, err)
+	}
+	if err := unmarshalIntoString(`[1, 2, 3, 4]`, &array); err == nil {
+		t.Error("UnmarshalInto [3]int accepted four elements")
+	}
+	d := NewDecoder(strings.NewReader(`[4, 5, 6, 7] 8`))
+	d.TruncateArrays = true
+	if err := d.DecodeInto(&array); err != nil || array != [3]int{4, 5, 6} {
+		t.Errorf("DecodeInto [3]int with TruncateArrays = %v, %v; want [4 5 6]", array, err)
+	}
+	var next int
+	if err := d.DecodeInto(&next); err != nil || next != 8 {
+		t.Errorf("DecodeInto after a truncated array = %v, %v; want 8", next, err)
+	}
+}
+
+func TestUnmarshalIntoRemainingField(t *testing.T) {
+	var target struct {
+		Name  string                 `json:"name"`
+		Count int                    `json:"count"`
+		Extra map[string]interface{} `json:"extra,remaining"`
+	}
+	err := unmarshalIntoString(`{"name": "a", "x": [1], "count": 2, "y": {"z": null}}`, &target)
+	if err != nil {
+		t.Fatalf("UnmarshalInto failed: %v", err)
+	}
+	want := map[string]interface{}{"x": []interface{}{int64(1)}, "y": map[string]interface{}{"z": nil}}
+	if target.Name != "a" || target.Count != 2 || !Equal(target.Extra, want) {
+		t.Errorf("UnmarshalInto = %+v; want name a, count 2, and extra %v", target, want)
+	}
+	data, err := Marshal(target)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	parsed, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+	}
+	want["name"], want["count"] = "a", int64(2)
+	if !Equal(parsed, want) {
+		t.Errorf("Marshal = %s; want the remaining members alongside the fields", data)
+	}
+}
+
+func TestUnmarshalIntoIntegerFromExponent(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"1e3", 1000},
+		{"-2E2", -200},
+		{"1.0", 1},
+		{"100e-2", 1},
+		{"9.2233720368547758e18", 9223372036854775800},
+	}
+	for _, test := range tests {
+		var value int64
+		if err := unmarshalIntoString(test.input, &value); err != nil || value != test.want {
+			t.Errorf("UnmarshalInto int64 from %s = %d, %v; want %d", test.input, value, err, test.want)
+		}
+	}
+	for _, input := range []string{"1.5", "1e-2", "1e19"} {
+		var value int64
+		if err := unmarshalIntoString(input, &value); err == nil {
+			t.Errorf("UnmarshalInto int64 accepted %s as %d", input, value)
+		}
+	}
+}
+
+func TestUnmarshalIntoIntegerPrecision(t *testing.T) {
+	var signed int64
+	if err := unmarshalIntoString("9007199254740993", &signed); err != nil || signed != 9007199254740993 {
+		t.Errorf("UnmarshalInto int64 = %d, %v; want 9007199254740993", signed, err)
+	}
+	if err := unmarshalIntoString("-9223372036854775808", &signed); err != nil || signed != -9223372036854775808 {
+		t.Errorf("UnmarshalInto int64 = %d, %v; want the minimum int64", signed, err)
+	}
+	var unsigned uint64
+	if err := unmarshalIntoString("18446744073709551615", &unsigned); err != nil || unsigned != 18446744073709551615 {
+		t.Errorf("UnmarshalInto uint64 = %d, %v; want the maximum uint64", unsigned, err)
+	}
+	if err := unmarshalIntoString("18446744073709551616", &unsigned); err == nil {
+		t.Errorf("UnmarshalInto uint64 accepted 2^64 as %d", unsigned)
+	}
+	var small int8
+	if err := unmarshalIntoString("128", &small); err == nil {
+		t.Errorf("UnmarshalInto int8 accepted 128 as %d", small)
+	}
+}
+
+// decodeClock decodes an "HH:MM" string into a time.Duration.
+func decodeClock(raw []byte, target reflect.Value) error {
+	value, err := Unmarshal(raw)
+	if err != nil {
+		return err
+	}
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected an HH:MM string, found %s", raw)
+	}
+	var hours, minutes int
+	if _, err := fmt.Sscanf(text, "%d:%d", &hours, &minutes); err != nil {
+		return fmt.Errorf("invalid HH:MM string %q: %w", text, err)
+	}
+	target.SetInt(int64(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute))
+	return nil
+}
+
+func TestDecoderRegisterTypeDecoder(t *testing.T) {
+	var target struct {
+		Start  time.Duration
+		Breaks []time.Duration
+		Count  int
+	}
+	d := NewDecoder(strings.NewReader(`{"Start": "09:30", "Breaks": ["12:00", "15:15"], "Count": 3}`))
+	d.RegisterTypeDecoder(reflect.TypeOf(time.Duration(0)), decodeClock)
+	if err := d.DecodeInto(&target); err != nil {
+		t.Fatalf("DecodeInto failed: %v", err)
+	}
+	if target.Start != 9*time.Hour+30*time.Minute || target.Count != 3 ||
+		!reflect.DeepEqual(target.Breaks, []time.Duration{12 * time.Hour, 15*time.Hour + 15*time.Minute}) {
+		t.Errorf("DecodeInto = %+v", target)
+	}
+	d = NewDecoder(strings.NewReader(`{"Start": 5}`))
+	d.RegisterTypeDecoder(reflect.TypeOf(time.Duration(0)), decodeClock)
+	if err := d.DecodeInto(&target); err == nil || !strings.Contains(err.Error(), "expected an HH:MM string") {
+		t.Errorf("DecodeInto = %v; want the registered decoder's error", err)
+	}
+}
+
+type testNode struct {
+	Kids []testNode
+}
+
+func TestUnmarshalIntoNestedErrorDescribedOnce(t *testing.T) {
+	var node testNode
+	err := unmarshalIntoString(strings.Repeat(`{"Kids":[`, 200)+`"x"`, &node)
+	if err == nil {
+		t.Fatal("UnmarshalInto accepted a string for a struct")
+	}
+	if count := strings.Count(err.Error(), "failed to Unmarshal"); count > 2 {
+		t.Errorf("UnmarshalInto error is wrapped %d times; want the innermost level only: %v", count, err)
+	}
+	if !strings.Contains(err.Error(), "at $.Kids[0].Kids[0]") {
+		t.Errorf("UnmarshalInto error = %.200s; want the path of the failing element", err)
+	}
+}
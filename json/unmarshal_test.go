@@ -0,0 +1,229 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// oneByteReader returns a reader that yields a single byte per Read call, like a slow network stream.
+func oneByteReader(s string) *bufio.Reader {
+	return bufio.NewReader(iotest.OneByteReader(strings.NewReader(s)))
+}
+
+func TestUnmarshalLiteralsOneByteAtATime(t *testing.T) {
+	if value, err := UnmarshalNull(oneByteReader("null")); err != nil || value != nil {
+		t.Errorf("UnmarshalNull = %v, %v; want nil, nil", value, err)
+	}
+	if value, err := UnmarshalTrue(oneByteReader("true")); err != nil || !value {
+		t.Errorf("UnmarshalTrue = %v, %v; want true, nil", value, err)
+	}
+	if value, err := UnmarshalFalse(oneByteReader("false")); err != nil || value {
+		t.Errorf("UnmarshalFalse = %v, %v; want false, nil", value, err)
+	}
+	value, err := UnmarshalValue(oneByteReader(`[null, true, false]`))
+	if err != nil {
+		t.Fatalf("UnmarshalValue failed: %v", err)
+	}
+	if !Equal(value, []interface{}{nil, true, false}) {
+		t.Errorf("UnmarshalValue = %#v", value)
+	}
+}
+
+func TestUnmarshalDeepNestingFails(t *testing.T) {
+	_, err := Unmarshal([]byte(strings.Repeat("[", 100000)))
+	if err == nil || !strings.Contains(err.Error(), "exceeded maximum nesting depth") {
+		t.Errorf("Unmarshal of 100000 nested arrays = %v; want a nesting depth error", err)
+	}
+	d := NewDecoder(strings.NewReader(strings.Repeat(`{"a":`, 100)))
+	d.MaxDepth = 10
+	if _, err := d.Decode(); err == nil || !strings.Contains(err.Error(), "exceeded maximum nesting depth of 10") {
+		t.Errorf("Decode of 100 nested objects with MaxDepth 10 = %v; want a nesting depth error", err)
+	}
+	d = NewDecoder(strings.NewReader(`[[[1]]]`))
+	d.MaxDepth = 2
+	if _, err := d.Decode(); err == nil {
+		t.Error("Decode with MaxDepth 2 accepted three levels of nesting")
+	}
+	d = NewDecoder(strings.NewReader(`[[1]]`))
+	d.MaxDepth = 2
+	if _, err := d.Decode(); err != nil {
+		t.Errorf("Decode with MaxDepth 2 rejected two levels of nesting: %v", err)
+	}
+}
+
+func TestUnmarshalNumberRejectsLeadingZeros(t *testing.T) {
+	for _, input := range []string{"01", "-00", "007"} {
+		_, err := UnmarshalNumber(bufio.NewReader(strings.NewReader(input)))
+		if err == nil || !strings.Contains(err.Error(), "leading zeros are not allowed in number: "+input) {
+			t.Errorf("UnmarshalNumber(%q) = %v; want a leading zeros error naming the whole token", input, err)
+		}
+	}
+	for _, input := range []string{"0", "-0", "0.5", "0e1"} {
+		if _, err := UnmarshalNumber(bufio.NewReader(strings.NewReader(input))); err != nil {
+			t.Errorf("UnmarshalNumber(%q) failed: %v", input, err)
+		}
+	}
+}
+
+func TestUnmarshalUnicodeEscapeOneByteAtATime(t *testing.T) {
+	value, err := UnmarshalString(oneByteReader(`"caf\u00e9 \ud83d\ude00"`))
+	if err != nil {
+		t.Fatalf("UnmarshalString failed: %v", err)
+	}
+	if value != "café 😀" {
+		t.Errorf("UnmarshalString = %q; want %q", value, "café 😀")
+	}
+}
+
+func TestUnmarshalTruncatedInput(t *testing.T) {
+	inputs := []string{
+		`{`, `{"a"`, `{"a":`, `{"a": 1`, `{"a": 1,`, `{"a`, `{"a": "b`,
+		`"abc`, `"ab\`, `"ab\u00`, `"\ud83d`, `"\ud83d\u`,
+		`[`, `[1, 2`, `[1,`, `tr`, `-`, `1.`, `1e+`,
+	}
+	for _, input := range inputs {
+		_, err := UnmarshalValue(bufio.NewReader(strings.NewReader(input)))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("UnmarshalValue(%q) = %v; want an error wrapping io.ErrUnexpectedEOF", input, err)
+		}
+	}
+	for _, input := range []string{`{"a" 1}`, `[1 2]`, `"\x"`} {
+		_, err := UnmarshalValue(bufio.NewReader(strings.NewReader(input)))
+		if err == nil || errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("UnmarshalValue(%q) = %v; want a syntax error that is not io.ErrUnexpectedEOF", input, err)
+		}
+	}
+}
+
+func TestUnmarshalCompleteTopLevelScalars(t *testing.T) {
+	tests := []struct {
+		input string
+		want  interface{}
+	}{
+		{"true", true},
+		{" 42 \n", int64(42)},
+		{`"x"`, "x"},
+		{"null", nil},
+	}
+	for _, test := range tests {
+		value, err := UnmarshalComplete(bufio.NewReader(strings.NewReader(test.input)))
+		if err != nil || value != test.want {
+			t.Errorf("UnmarshalComplete(%q) = %#v, %v; want %#v", test.input, value, err, test.want)
+		}
+		if !Valid([]byte(test.input)) {
+			t.Errorf("Valid(%q) = false", test.input)
+		}
+	}
+	for _, input := range []string{"true false", "42 x", "null,", `"x""y"`} {
+		_, err := UnmarshalComplete(bufio.NewReader(strings.NewReader(input)))
+		if err == nil || !strings.Contains(err.Error(), "unexpected trailing data after top-level value") {
+			t.Errorf("UnmarshalComplete(%q) = %v; want a trailing data error", input, err)
+		}
+		if Valid([]byte(input)) {
+			t.Errorf("Valid(%q) = true", input)
+		}
+	}
+}
+
+// numberArray returns a JSON array of count numbers, each formatted by format.
+func numberArray(count int, format func(i int) string) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(format(i))
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+func benchmarkUnmarshalReader(b *testing.B, data []byte) {
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := UnmarshalValue(bufio.NewReader(bytes.NewReader(data))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshalIntegers decodes 10000 integers, which are parsed straight from the reader's buffer.
+func BenchmarkUnmarshalIntegers(b *testing.B) {
+	benchmarkUnmarshalReader(b, numberArray(10000, func(i int) string {
+		return strconv.Itoa(i * 7919)
+	}))
+}
+
+// BenchmarkUnmarshalFloats decodes 10000 numbers with a fraction, which go through the rune-by-rune path, for
+// comparison with BenchmarkUnmarshalIntegers.
+func BenchmarkUnmarshalFloats(b *testing.B) {
+	benchmarkUnmarshalReader(b, numberArray(10000, func(i int) string {
+		return strconv.Itoa(i*7919) + ".5"
+	}))
+}
+
+// recordsDocument is an array of 1000 small objects, about 100KB.
+var recordsDocument = func() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"id":%d,"name":"user %d","email":"u%d@example.com","active":true,"score":%d.5,"tags":["a","b"]}`,
+			i, i, i, i)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}()
+
+// BenchmarkUnmarshalRecords decodes many objects that share their keys, which are interned.
+func BenchmarkUnmarshalRecords(b *testing.B) {
+	benchmarkUnmarshalReader(b, recordsDocument)
+}
+
+// BenchmarkUnmarshalWideObject decodes one object with 5000 members.
+func BenchmarkUnmarshalWideObject(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i < 5000; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `"key%d":%d`, i, i)
+	}
+	buf.WriteByte('}')
+	benchmarkUnmarshalReader(b, buf.Bytes())
+}
+
+// BenchmarkUnmarshalBytes parses the records document in place.
+func BenchmarkUnmarshalBytes(b *testing.B) {
+	b.SetBytes(int64(len(recordsDocument)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := UnmarshalBytes(recordsDocument); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshalBytesReader parses the records document through a bufio.Reader, for comparison with
+// BenchmarkUnmarshalBytes.
+func BenchmarkUnmarshalBytesReader(b *testing.B) {
+	b.SetBytes(int64(len(recordsDocument)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := UnmarshalComplete(bufio.NewReader(bytes.NewReader(recordsDocument))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
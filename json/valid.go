@@ -0,0 +1,31 @@
+package json
+
+import "io"
+
+// Valid reports whether data is a single well-formed JSON value with nothing after it but whitespace.
+func Valid(data []byte) bool {
+	return ValidError(data) == nil
+}
+
+// ValidError is like Valid but explains the first problem found. It returns nil for valid input and otherwise a
+// *SyntaxError whose Offset locates the problem.
+func ValidError(data []byte) error {
+	d := newBytesDecoder(data)
+	return d.wrapError(d.validate())
+}
+
+// validate consumes a single value token by token without building it, failing on anything after it but whitespace.
+func (d *Decoder) validate() error {
+	d.UseNumber = true
+	for {
+		if _, err := d.token(); err == io.EOF {
+			return EMPTY_INPUT
+		} else if err != nil {
+			return err
+		}
+		if len(d.tokenStack) == 0 {
+			break
+		}
+	}
+	return d.expectEOF()
+}